@@ -0,0 +1,17 @@
+#!/bin/sh
+# Creates the signing key and the share link secret on first start, applies
+# pending migrations and serves. Both secrets live in the secrets volume, so
+# tokens and share links survive container rebuilds.
+set -e
+
+mkdir -p "$JWT_KEYS_DIR"
+if ! ls "$JWT_KEYS_DIR"/*.pem >/dev/null 2>&1; then
+	openssl genpkey -algorithm ed25519 -out "$JWT_KEYS_DIR/$(date +%Y-%m).pem"
+fi
+
+if [ ! -s "$SHARE_LINK_SECRET_FILE" ]; then
+	(umask 077 && openssl rand -hex 32 > "$SHARE_LINK_SECRET_FILE")
+fi
+
+/app/cmd/main migrate up
+exec /app/cmd/main "$@"
@@ -0,0 +1,37 @@
+from django.contrib import admin
+from .models import APIKey, OIDCIdentity, RateLimitBucket, Session, TOTPDevice
+
+
+@admin.register(Session)
+class SessionAdmin(admin.ModelAdmin):
+    list_display = ('id', 'user', 'ip_address', 'mfa_verified', 'created_at', 'last_used_at', 'expires_at', 'revoked_at')
+    search_fields = ('user__username', 'ip_address')
+    list_filter = ('revoked_at',)
+
+
+@admin.register(OIDCIdentity)
+class OIDCIdentityAdmin(admin.ModelAdmin):
+    list_display = ('id', 'user', 'issuer', 'subject', 'created_at')
+    search_fields = ('user__username', 'subject')
+    list_filter = ('issuer',)
+
+
+@admin.register(APIKey)
+class APIKeyAdmin(admin.ModelAdmin):
+    list_display = ('id', 'user', 'name', 'prefix', 'read_only', 'project', 'expires_at', 'last_used_at', 'revoked_at')
+    search_fields = ('user__username', 'name', 'prefix')
+    list_filter = ('read_only', 'revoked_at')
+    readonly_fields = ('key_hash',)
+
+
+@admin.register(RateLimitBucket)
+class RateLimitBucketAdmin(admin.ModelAdmin):
+    list_display = ('key', 'count', 'window_ends_at', 'blocked_until')
+    search_fields = ('key',)
+
+
+@admin.register(TOTPDevice)
+class TOTPDeviceAdmin(admin.ModelAdmin):
+    list_display = ('user', 'confirmed_at', 'created_at')
+    search_fields = ('user__username',)
+    exclude = ('secret',)
@@ -0,0 +1,49 @@
+# Generated by Django 5.1.3 on 2026-10-19 13:00
+
+import django.db.models.deletion
+from django.conf import settings
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    initial = True
+
+    dependencies = [
+        migrations.swappable_dependency(settings.AUTH_USER_MODEL),
+    ]
+
+    operations = [
+        migrations.CreateModel(
+            name='Session',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('user_agent', models.CharField(blank=True, default='', max_length=512, verbose_name='User-Agent')),
+                ('ip_address', models.CharField(blank=True, default='', max_length=64, verbose_name='IP-адрес')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создана')),
+                ('last_used_at', models.DateTimeField(auto_now_add=True, verbose_name='Последнее использование')),
+                ('expires_at', models.DateTimeField(verbose_name='Истекает')),
+                ('revoked_at', models.DateTimeField(blank=True, null=True, verbose_name='Отозвана')),
+                ('user', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+            ],
+            options={
+                'verbose_name': 'Сессия',
+                'verbose_name_plural': 'Сессии',
+            },
+        ),
+        migrations.CreateModel(
+            name='RefreshToken',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('token_hash', models.CharField(max_length=64, unique=True, verbose_name='Хэш токена')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создан')),
+                ('expires_at', models.DateTimeField(verbose_name='Истекает')),
+                ('used_at', models.DateTimeField(blank=True, null=True, verbose_name='Использован')),
+                ('session', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to='accounts.session', verbose_name='Сессия')),
+            ],
+            options={
+                'verbose_name': 'Refresh-токен',
+                'verbose_name_plural': 'Refresh-токены',
+            },
+        ),
+    ]
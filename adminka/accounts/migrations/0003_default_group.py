@@ -0,0 +1,56 @@
+# Generated by Django 5.1.3 on 2026-10-19 15:00
+
+from django.contrib.auth.management import create_permissions
+from django.db import migrations
+
+DEFAULT_GROUP = 'Users'
+
+# Permissions the Go API checks on project routes; staff-only routes are left out.
+DEFAULT_PERMISSIONS = [
+    'view_project', 'add_project',
+    'add_building', 'change_building',
+    'add_playground', 'change_playground',
+    'change_editlock',
+    'view_comment', 'add_comment', 'change_comment',
+    'view_projectuser', 'add_projectuser', 'change_projectuser', 'delete_projectuser',
+    'view_sharelink', 'add_sharelink', 'change_sharelink',
+]
+
+
+def create_default_group(apps, schema_editor):
+    # Permissions are normally created after migrate, make sure they exist now.
+    for app_config in apps.get_app_configs():
+        app_config.models_module = True
+        create_permissions(app_config, apps=apps, verbosity=0)
+        app_config.models_module = None
+
+    Group = apps.get_model('auth', 'Group')
+    Permission = apps.get_model('auth', 'Permission')
+    User = apps.get_model('auth', 'User')
+
+    group, _ = Group.objects.get_or_create(name=DEFAULT_GROUP)
+    group.permissions.add(*Permission.objects.filter(
+        content_type__app_label='projects',
+        codename__in=DEFAULT_PERMISSIONS,
+    ))
+    for user in User.objects.all():
+        user.groups.add(group)
+
+
+def delete_default_group(apps, schema_editor):
+    Group = apps.get_model('auth', 'Group')
+    Group.objects.filter(name=DEFAULT_GROUP).delete()
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('accounts', '0002_passwordresettoken'),
+        ('auth', '0012_alter_user_first_name_max_length'),
+        ('contenttypes', '0002_remove_content_type_name'),
+        ('projects', '0007_alter_project_organisation'),
+    ]
+
+    operations = [
+        migrations.RunPython(create_default_group, delete_default_group),
+    ]
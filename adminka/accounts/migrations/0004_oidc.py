@@ -0,0 +1,46 @@
+# Generated by Django 5.1.3 on 2026-10-19 16:00
+
+import django.db.models.deletion
+from django.conf import settings
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('accounts', '0003_default_group'),
+        migrations.swappable_dependency(settings.AUTH_USER_MODEL),
+    ]
+
+    operations = [
+        migrations.CreateModel(
+            name='OIDCLoginState',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('state_hash', models.CharField(max_length=64, unique=True, verbose_name='Хэш state')),
+                ('code_verifier', models.CharField(max_length=128, verbose_name='PKCE code verifier')),
+                ('nonce', models.CharField(max_length=128, verbose_name='Nonce')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создан')),
+                ('expires_at', models.DateTimeField(verbose_name='Истекает')),
+            ],
+            options={
+                'verbose_name': 'Незавершённый OIDC-вход',
+                'verbose_name_plural': 'Незавершённые OIDC-входы',
+            },
+        ),
+        migrations.CreateModel(
+            name='OIDCIdentity',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('issuer', models.CharField(max_length=255, verbose_name='Провайдер')),
+                ('subject', models.CharField(max_length=255, verbose_name='Идентификатор у провайдера')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Привязана')),
+                ('user', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+            ],
+            options={
+                'verbose_name': 'OIDC-идентичность',
+                'verbose_name_plural': 'OIDC-идентичности',
+                'constraints': [models.UniqueConstraint(fields=('issuer', 'subject'), name='unique_oidc_identity')],
+            },
+        ),
+    ]
@@ -0,0 +1,27 @@
+# Generated by Django 5.1.3 on 2026-10-19 18:00
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('accounts', '0005_apikey'),
+    ]
+
+    operations = [
+        migrations.CreateModel(
+            name='RateLimitBucket',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('key', models.CharField(max_length=255, unique=True, verbose_name='Ключ')),
+                ('count', models.IntegerField(default=0, verbose_name='Счётчик')),
+                ('window_ends_at', models.DateTimeField(verbose_name='Окно до')),
+                ('blocked_until', models.DateTimeField(blank=True, null=True, verbose_name='Заблокирован до')),
+            ],
+            options={
+                'verbose_name': 'Ограничение частоты запросов',
+                'verbose_name_plural': 'Ограничения частоты запросов',
+            },
+        ),
+    ]
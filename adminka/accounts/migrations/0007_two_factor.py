@@ -0,0 +1,71 @@
+# Generated by Django 5.1.3 on 2026-10-19 19:00
+
+import django.db.models.deletion
+from django.conf import settings
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('accounts', '0006_ratelimitbucket'),
+        migrations.swappable_dependency(settings.AUTH_USER_MODEL),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name='session',
+            name='mfa_verified',
+            field=models.BooleanField(default=False, verbose_name='Вход с 2FA'),
+        ),
+        migrations.AddField(
+            model_name='apikey',
+            name='mfa_verified',
+            field=models.BooleanField(default=False, verbose_name='Создан в сессии с 2FA'),
+        ),
+        migrations.CreateModel(
+            name='TOTPDevice',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('secret', models.CharField(max_length=64, verbose_name='Секрет')),
+                ('confirmed_at', models.DateTimeField(blank=True, null=True, verbose_name='Подтверждено')),
+                ('last_used_step', models.BigIntegerField(default=0, verbose_name='Последний использованный шаг')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создано')),
+                ('user', models.OneToOneField(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+            ],
+            options={
+                'verbose_name': 'TOTP-устройство',
+                'verbose_name_plural': 'TOTP-устройства',
+            },
+        ),
+        migrations.CreateModel(
+            name='RecoveryCode',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('code_hash', models.CharField(max_length=64, verbose_name='Хэш кода')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создан')),
+                ('used_at', models.DateTimeField(blank=True, null=True, verbose_name='Использован')),
+                ('user', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+            ],
+            options={
+                'verbose_name': 'Код восстановления',
+                'verbose_name_plural': 'Коды восстановления',
+            },
+        ),
+        migrations.CreateModel(
+            name='MFAChallenge',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('token_hash', models.CharField(max_length=64, unique=True, verbose_name='Хэш токена')),
+                ('attempts', models.IntegerField(default=0, verbose_name='Попытки')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создан')),
+                ('expires_at', models.DateTimeField(verbose_name='Истекает')),
+                ('used_at', models.DateTimeField(blank=True, null=True, verbose_name='Использован')),
+                ('user', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+            ],
+            options={
+                'verbose_name': 'Запрос второго фактора',
+                'verbose_name_plural': 'Запросы второго фактора',
+            },
+        ),
+    ]
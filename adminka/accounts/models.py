@@ -0,0 +1,132 @@
+from django.contrib.auth.models import User
+from django.db import models
+
+
+class Session(models.Model):
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    user_agent = models.CharField(max_length=512, blank=True, default='', verbose_name="User-Agent")
+    ip_address = models.CharField(max_length=64, blank=True, default='', verbose_name="IP-адрес")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создана")
+    last_used_at = models.DateTimeField(auto_now_add=True, verbose_name="Последнее использование")
+    expires_at = models.DateTimeField(verbose_name="Истекает")
+    revoked_at = models.DateTimeField(null=True, blank=True, verbose_name="Отозвана")
+    mfa_verified = models.BooleanField(default=False, verbose_name="Вход с 2FA")
+
+    class Meta:
+        verbose_name = "Сессия"
+        verbose_name_plural = "Сессии"
+
+
+class RefreshToken(models.Model):
+    session = models.ForeignKey(Session, on_delete=models.CASCADE, verbose_name="Сессия")
+    token_hash = models.CharField(max_length=64, unique=True, verbose_name="Хэш токена")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создан")
+    expires_at = models.DateTimeField(verbose_name="Истекает")
+    used_at = models.DateTimeField(null=True, blank=True, verbose_name="Использован")
+
+    class Meta:
+        verbose_name = "Refresh-токен"
+        verbose_name_plural = "Refresh-токены"
+
+
+class PasswordResetToken(models.Model):
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    token_hash = models.CharField(max_length=64, unique=True, verbose_name="Хэш токена")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создан")
+    expires_at = models.DateTimeField(verbose_name="Истекает")
+    used_at = models.DateTimeField(null=True, blank=True, verbose_name="Использован")
+
+    class Meta:
+        verbose_name = "Токен сброса пароля"
+        verbose_name_plural = "Токены сброса пароля"
+
+
+class OIDCIdentity(models.Model):
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    issuer = models.CharField(max_length=255, verbose_name="Провайдер")
+    subject = models.CharField(max_length=255, verbose_name="Идентификатор у провайдера")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Привязана")
+
+    class Meta:
+        verbose_name = "OIDC-идентичность"
+        verbose_name_plural = "OIDC-идентичности"
+        constraints = [
+            models.UniqueConstraint(fields=['issuer', 'subject'], name='unique_oidc_identity'),
+        ]
+
+
+class OIDCLoginState(models.Model):
+    state_hash = models.CharField(max_length=64, unique=True, verbose_name="Хэш state")
+    code_verifier = models.CharField(max_length=128, verbose_name="PKCE code verifier")
+    nonce = models.CharField(max_length=128, verbose_name="Nonce")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создан")
+    expires_at = models.DateTimeField(verbose_name="Истекает")
+
+    class Meta:
+        verbose_name = "Незавершённый OIDC-вход"
+        verbose_name_plural = "Незавершённые OIDC-входы"
+
+
+class APIKey(models.Model):
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    name = models.CharField(max_length=100, verbose_name="Название")
+    prefix = models.CharField(max_length=16, verbose_name="Префикс")
+    key_hash = models.CharField(max_length=64, unique=True, verbose_name="Хэш ключа")
+    read_only = models.BooleanField(default=False, verbose_name="Только чтение")
+    project = models.ForeignKey('projects.Project', on_delete=models.CASCADE, null=True, blank=True, verbose_name="Проект")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создан")
+    expires_at = models.DateTimeField(null=True, blank=True, verbose_name="Истекает")
+    last_used_at = models.DateTimeField(null=True, blank=True, verbose_name="Последнее использование")
+    revoked_at = models.DateTimeField(null=True, blank=True, verbose_name="Отозван")
+    mfa_verified = models.BooleanField(default=False, verbose_name="Создан в сессии с 2FA")
+
+    class Meta:
+        verbose_name = "API-ключ"
+        verbose_name_plural = "API-ключи"
+
+
+class RateLimitBucket(models.Model):
+    key = models.CharField(max_length=255, unique=True, verbose_name="Ключ")
+    count = models.IntegerField(default=0, verbose_name="Счётчик")
+    window_ends_at = models.DateTimeField(verbose_name="Окно до")
+    blocked_until = models.DateTimeField(null=True, blank=True, verbose_name="Заблокирован до")
+
+    class Meta:
+        verbose_name = "Ограничение частоты запросов"
+        verbose_name_plural = "Ограничения частоты запросов"
+
+
+class TOTPDevice(models.Model):
+    user = models.OneToOneField(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    secret = models.CharField(max_length=64, verbose_name="Секрет")
+    confirmed_at = models.DateTimeField(null=True, blank=True, verbose_name="Подтверждено")
+    last_used_step = models.BigIntegerField(default=0, verbose_name="Последний использованный шаг")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создано")
+
+    class Meta:
+        verbose_name = "TOTP-устройство"
+        verbose_name_plural = "TOTP-устройства"
+
+
+class RecoveryCode(models.Model):
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    code_hash = models.CharField(max_length=64, verbose_name="Хэш кода")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создан")
+    used_at = models.DateTimeField(null=True, blank=True, verbose_name="Использован")
+
+    class Meta:
+        verbose_name = "Код восстановления"
+        verbose_name_plural = "Коды восстановления"
+
+
+class MFAChallenge(models.Model):
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    token_hash = models.CharField(max_length=64, unique=True, verbose_name="Хэш токена")
+    attempts = models.IntegerField(default=0, verbose_name="Попытки")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создан")
+    expires_at = models.DateTimeField(verbose_name="Истекает")
+    used_at = models.DateTimeField(null=True, blank=True, verbose_name="Использован")
+
+    class Meta:
+        verbose_name = "Запрос второго фактора"
+        verbose_name_plural = "Запросы второго фактора"
@@ -0,0 +1,16 @@
+from django.contrib.auth.models import Group, User
+from django.db.models.signals import post_save
+from django.dispatch import receiver
+
+# Created by migration 0003_default_group.
+DEFAULT_GROUP = 'Users'
+
+
+@receiver(post_save, sender=User)
+def add_to_default_group(sender, instance, created, raw, **kwargs):
+    """New users, also those created in the admin, join the default group."""
+    if not created or raw:
+        return
+    group = Group.objects.filter(name=DEFAULT_GROUP).first()
+    if group is not None:
+        instance.groups.add(group)
@@ -1,12 +1,30 @@
 from django.contrib import admin
-from .models import Project, Playground, Building
+from .models import Organisation, OrganisationMember, Project, ProjectUser, Playground, Building, EditLock, Comment, ShareLink
+
+
+class OrganisationMemberInline(admin.TabularInline):
+    model = OrganisationMember
+    extra = 1
+
+
+@admin.register(Organisation)
+class OrganisationAdmin(admin.ModelAdmin):
+    list_display = ('id', 'name', 'created_at')
+    inlines = (OrganisationMemberInline,)
+    search_fields = ('name', 'members__username')
+
+
+class ProjectUserInline(admin.TabularInline):
+    model = ProjectUser
+    extra = 1
 
 
 @admin.register(Project)
 class ProjectAdmin(admin.ModelAdmin):
-    list_display = ('id', 'name')
+    list_display = ('id', 'name', 'organisation')
+    inlines = (ProjectUserInline,)
     search_fields = ('name', 'user__username')
-    list_filter = ('user',)
+    list_filter = ('organisation', 'user')
 
 
 @admin.register(Playground)
@@ -20,3 +38,24 @@ class BuildingAdmin(admin.ModelAdmin):
     list_display = ('id', 'project', 'floors', 'floors_height', 'coordinates')
     search_fields = ('project__name',)
     list_filter = ('floors',)
+
+
+@admin.register(EditLock)
+class EditLockAdmin(admin.ModelAdmin):
+    list_display = ('id', 'project', 'object_type', 'object_id', 'user', 'expires_at')
+    search_fields = ('project__name', 'user__username')
+    list_filter = ('object_type',)
+
+
+@admin.register(Comment)
+class CommentAdmin(admin.ModelAdmin):
+    list_display = ('id', 'project', 'building', 'author', 'is_resolved', 'created_at')
+    search_fields = ('project__name', 'author__username', 'text')
+    list_filter = ('is_resolved',)
+
+
+@admin.register(ShareLink)
+class ShareLinkAdmin(admin.ModelAdmin):
+    list_display = ('id', 'project', 'created_by', 'created_at', 'expires_at', 'revoked_at')
+    search_fields = ('project__name', 'created_by__username')
+    exclude = ('token_hash', 'password')
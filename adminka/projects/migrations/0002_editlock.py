@@ -0,0 +1,33 @@
+# Generated by Django 5.1.3 on 2026-10-19 10:40
+
+import django.db.models.deletion
+from django.conf import settings
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('projects', '0001_initial'),
+        migrations.swappable_dependency(settings.AUTH_USER_MODEL),
+    ]
+
+    operations = [
+        migrations.CreateModel(
+            name='EditLock',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('object_type', models.CharField(choices=[('building', 'Здание'), ('playground', 'Площадка')], max_length=32, verbose_name='Тип объекта')),
+                ('object_id', models.BigIntegerField(verbose_name='ID объекта')),
+                ('acquired_at', models.DateTimeField(auto_now_add=True, verbose_name='Захвачена')),
+                ('expires_at', models.DateTimeField(verbose_name='Истекает')),
+                ('project', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to='projects.project', verbose_name='Проект')),
+                ('user', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+            ],
+            options={
+                'verbose_name': 'Блокировка',
+                'verbose_name_plural': 'Блокировки',
+                'constraints': [models.UniqueConstraint(fields=('object_type', 'object_id'), name='unique_edit_lock')],
+            },
+        ),
+    ]
@@ -0,0 +1,38 @@
+# Generated by Django 5.1.3 on 2026-10-19 11:05
+
+import django.db.models.deletion
+from django.conf import settings
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('projects', '0002_editlock'),
+        migrations.swappable_dependency(settings.AUTH_USER_MODEL),
+    ]
+
+    operations = [
+        migrations.CreateModel(
+            name='Comment',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('text', models.TextField(verbose_name='Текст')),
+                ('point_x', models.FloatField(blank=True, null=True, verbose_name='Точка X')),
+                ('point_y', models.FloatField(blank=True, null=True, verbose_name='Точка Y')),
+                ('is_resolved', models.BooleanField(default=False, verbose_name='Решён')),
+                ('resolved_at', models.DateTimeField(blank=True, null=True, verbose_name='Когда решён')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создан')),
+                ('author', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, related_name='project_comments', to=settings.AUTH_USER_MODEL, verbose_name='Автор')),
+                ('building', models.ForeignKey(blank=True, null=True, on_delete=django.db.models.deletion.CASCADE, to='projects.building', verbose_name='Здание')),
+                ('mentions', models.ManyToManyField(blank=True, related_name='project_comment_mentions', to=settings.AUTH_USER_MODEL, verbose_name='Упоминания')),
+                ('parent', models.ForeignKey(blank=True, null=True, on_delete=django.db.models.deletion.CASCADE, related_name='replies', to='projects.comment', verbose_name='Ответ на')),
+                ('project', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to='projects.project', verbose_name='Проект')),
+                ('resolved_by', models.ForeignKey(blank=True, null=True, on_delete=django.db.models.deletion.SET_NULL, related_name='resolved_project_comments', to=settings.AUTH_USER_MODEL, verbose_name='Кем решён')),
+            ],
+            options={
+                'verbose_name': 'Комментарий',
+                'verbose_name_plural': 'Комментарии',
+            },
+        ),
+    ]
@@ -0,0 +1,47 @@
+# Generated by Django 5.1.3 on 2026-10-19 11:30
+
+import django.db.models.deletion
+from django.conf import settings
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('projects', '0003_comment'),
+        migrations.swappable_dependency(settings.AUTH_USER_MODEL),
+    ]
+
+    operations = [
+        # projects_project_user already exists as the auto-created M2M table,
+        # so only the model state is switched to an explicit through model.
+        migrations.SeparateDatabaseAndState(
+            database_operations=[],
+            state_operations=[
+                migrations.CreateModel(
+                    name='ProjectUser',
+                    fields=[
+                        ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                        ('project', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to='projects.project', verbose_name='Проект')),
+                        ('user', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+                    ],
+                    options={
+                        'verbose_name': 'Участник проекта',
+                        'verbose_name_plural': 'Участники проекта',
+                        'db_table': 'projects_project_user',
+                        'unique_together': {('project', 'user')},
+                    },
+                ),
+                migrations.AlterField(
+                    model_name='project',
+                    name='user',
+                    field=models.ManyToManyField(through='projects.ProjectUser', to=settings.AUTH_USER_MODEL, verbose_name='Пользователь'),
+                ),
+            ],
+        ),
+        migrations.AddField(
+            model_name='projectuser',
+            name='role',
+            field=models.CharField(choices=[('owner', 'Владелец'), ('editor', 'Редактор'), ('viewer', 'Наблюдатель')], default='owner', max_length=16, verbose_name='Роль'),
+        ),
+    ]
@@ -0,0 +1,88 @@
+# Generated by Django 5.1.3 on 2026-10-19 12:30
+
+import django.db.models.deletion
+from django.conf import settings
+from django.db import migrations, models
+
+
+def create_owner_organisations(apps, schema_editor):
+    """Every existing project moves into an organisation of its owner.
+
+    The owner becomes the organisation's admin and the project's other users
+    join as members. A project with several owners goes to the one added first.
+    Projects without users share an organisation without members.
+    """
+    Organisation = apps.get_model('projects', 'Organisation')
+    OrganisationMember = apps.get_model('projects', 'OrganisationMember')
+    Project = apps.get_model('projects', 'Project')
+    ProjectUser = apps.get_model('projects', 'ProjectUser')
+    User = apps.get_model(settings.AUTH_USER_MODEL)
+
+    organisations = {}
+    for project in Project.objects.order_by('id'):
+        project_users = sorted(ProjectUser.objects.filter(project=project), key=lambda pu: (pu.role != 'owner', pu.id))
+        owner_id = project_users[0].user_id if project_users else None
+
+        organisation = organisations.get(owner_id)
+        if organisation is None:
+            name = User.objects.get(pk=owner_id).username if owner_id else 'По умолчанию'
+            organisation = Organisation.objects.create(name=name)
+            organisations[owner_id] = organisation
+
+        project.organisation = organisation
+        project.save(update_fields=['organisation'])
+
+        for project_user in project_users:
+            OrganisationMember.objects.get_or_create(
+                organisation=organisation,
+                user_id=project_user.user_id,
+                defaults={'role': 'admin' if project_user.user_id == owner_id else 'member'},
+            )
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('projects', '0005_sharelink'),
+        migrations.swappable_dependency(settings.AUTH_USER_MODEL),
+    ]
+
+    operations = [
+        migrations.CreateModel(
+            name='Organisation',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('name', models.CharField(max_length=255, verbose_name='Название')),
+                ('created_at', models.DateTimeField(auto_now_add=True, verbose_name='Создана')),
+            ],
+            options={
+                'verbose_name': 'Организация',
+                'verbose_name_plural': 'Организации',
+            },
+        ),
+        migrations.CreateModel(
+            name='OrganisationMember',
+            fields=[
+                ('id', models.BigAutoField(auto_created=True, primary_key=True, serialize=False, verbose_name='ID')),
+                ('role', models.CharField(choices=[('admin', 'Администратор'), ('member', 'Участник')], default='member', max_length=16, verbose_name='Роль')),
+                ('organisation', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to='projects.organisation', verbose_name='Организация')),
+                ('user', models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to=settings.AUTH_USER_MODEL, verbose_name='Пользователь')),
+            ],
+            options={
+                'verbose_name': 'Участник организации',
+                'verbose_name_plural': 'Участники организации',
+                'unique_together': {('organisation', 'user')},
+            },
+        ),
+        migrations.AddField(
+            model_name='organisation',
+            name='members',
+            field=models.ManyToManyField(through='projects.OrganisationMember', to=settings.AUTH_USER_MODEL, verbose_name='Участники'),
+        ),
+        migrations.AddField(
+            model_name='project',
+            name='organisation',
+            field=models.ForeignKey(null=True, on_delete=django.db.models.deletion.CASCADE, to='projects.organisation', verbose_name='Организация'),
+        ),
+        migrations.RunPython(create_owner_organisations, migrations.RunPython.noop),
+    ]
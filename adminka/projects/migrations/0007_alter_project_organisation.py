@@ -0,0 +1,19 @@
+# Generated by Django 5.1.3 on 2026-10-19 12:31
+
+import django.db.models.deletion
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('projects', '0006_organisation'),
+    ]
+
+    operations = [
+        migrations.AlterField(
+            model_name='project',
+            name='organisation',
+            field=models.ForeignKey(on_delete=django.db.models.deletion.CASCADE, to='projects.organisation', verbose_name='Организация'),
+        ),
+    ]
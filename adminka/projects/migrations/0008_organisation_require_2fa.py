@@ -0,0 +1,18 @@
+# Generated by Django 5.1.3 on 2026-10-19 19:00
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('projects', '0007_alter_project_organisation'),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name='organisation',
+            name='require_2fa',
+            field=models.BooleanField(default=False, verbose_name='Обязательная 2FA'),
+        ),
+    ]
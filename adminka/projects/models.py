@@ -2,15 +2,64 @@ from django.contrib.auth.models import User
 from django.db import models
 
 
+class Organisation(models.Model):
+    name = models.CharField(max_length=255, verbose_name="Название")
+    members = models.ManyToManyField(User, through='OrganisationMember', verbose_name="Участники")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создана")
+    require_2fa = models.BooleanField(default=False, verbose_name="Обязательная 2FA")
+
+    class Meta:
+        verbose_name = "Организация"
+        verbose_name_plural = "Организации"
+
+    def __str__(self):
+        return self.name
+
+
+class OrganisationMember(models.Model):
+    ROLES = (
+        ('admin', 'Администратор'),
+        ('member', 'Участник'),
+    )
+
+    organisation = models.ForeignKey(Organisation, on_delete=models.CASCADE, verbose_name="Организация")
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    role = models.CharField(max_length=16, choices=ROLES, default='member', verbose_name="Роль")
+
+    class Meta:
+        unique_together = (('organisation', 'user'),)
+        verbose_name = "Участник организации"
+        verbose_name_plural = "Участники организации"
+
+
 class Project(models.Model):
     name = models.CharField(max_length=255, default='Безымянный', verbose_name="Название")
-    user = models.ManyToManyField(User, verbose_name="Пользователь")
+    organisation = models.ForeignKey(Organisation, on_delete=models.CASCADE, verbose_name="Организация")
+    user = models.ManyToManyField(User, through='ProjectUser', verbose_name="Пользователь")
 
     class Meta:
         verbose_name = 'Проект'
         verbose_name_plural = "Проекты"
 
 
+class ProjectUser(models.Model):
+    ROLES = (
+        ('owner', 'Владелец'),
+        ('editor', 'Редактор'),
+        ('viewer', 'Наблюдатель'),
+    )
+
+    project = models.ForeignKey(Project, on_delete=models.CASCADE, verbose_name="Проект")
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    role = models.CharField(max_length=16, choices=ROLES, default='owner', verbose_name="Роль")
+
+    class Meta:
+        db_table = 'projects_project_user'
+        unique_together = (('project', 'user'),)
+        verbose_name = "Участник проекта"
+        verbose_name_plural = "Участники проекта"
+
+
 
 class Playground(models.Model):
     project = models.OneToOneField(Project, on_delete=models.CASCADE, verbose_name="Проект")
@@ -30,4 +79,57 @@ class Building(models.Model):
 
     class Meta:
         verbose_name = "Здание"
-        verbose_name_plural = "Здания"
\ No newline at end of file
+        verbose_name_plural = "Здания"
+
+class EditLock(models.Model):
+    OBJECT_TYPES = (
+        ('building', 'Здание'),
+        ('playground', 'Площадка'),
+    )
+
+    project = models.ForeignKey(Project, on_delete=models.CASCADE, verbose_name="Проект")
+    object_type = models.CharField(max_length=32, choices=OBJECT_TYPES, verbose_name="Тип объекта")
+    object_id = models.BigIntegerField(verbose_name="ID объекта")
+    user = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Пользователь")
+    acquired_at = models.DateTimeField(auto_now_add=True, verbose_name="Захвачена")
+    expires_at = models.DateTimeField(verbose_name="Истекает")
+
+    class Meta:
+        verbose_name = "Блокировка"
+        verbose_name_plural = "Блокировки"
+        constraints = [
+            models.UniqueConstraint(fields=['object_type', 'object_id'], name='unique_edit_lock'),
+        ]
+
+
+class Comment(models.Model):
+    project = models.ForeignKey(Project, on_delete=models.CASCADE, verbose_name="Проект")
+    building = models.ForeignKey(Building, on_delete=models.CASCADE, null=True, blank=True, verbose_name="Здание")
+    parent = models.ForeignKey('self', on_delete=models.CASCADE, null=True, blank=True, related_name='replies', verbose_name="Ответ на")
+    author = models.ForeignKey(User, on_delete=models.CASCADE, related_name='project_comments', verbose_name="Автор")
+    text = models.TextField(verbose_name="Текст")
+    point_x = models.FloatField(null=True, blank=True, verbose_name="Точка X")
+    point_y = models.FloatField(null=True, blank=True, verbose_name="Точка Y")
+    mentions = models.ManyToManyField(User, blank=True, related_name='project_comment_mentions', verbose_name="Упоминания")
+    is_resolved = models.BooleanField(default=False, verbose_name="Решён")
+    resolved_by = models.ForeignKey(User, on_delete=models.SET_NULL, null=True, blank=True, related_name='resolved_project_comments', verbose_name="Кем решён")
+    resolved_at = models.DateTimeField(null=True, blank=True, verbose_name="Когда решён")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создан")
+
+    class Meta:
+        verbose_name = "Комментарий"
+        verbose_name_plural = "Комментарии"
+
+
+class ShareLink(models.Model):
+    project = models.ForeignKey(Project, on_delete=models.CASCADE, verbose_name="Проект")
+    token_hash = models.CharField(max_length=64, unique=True, verbose_name="Хэш токена")
+    password = models.CharField(max_length=128, blank=True, default='', verbose_name="Пароль")
+    created_by = models.ForeignKey(User, on_delete=models.CASCADE, verbose_name="Создатель")
+    created_at = models.DateTimeField(auto_now_add=True, verbose_name="Создана")
+    expires_at = models.DateTimeField(null=True, blank=True, verbose_name="Истекает")
+    revoked_at = models.DateTimeField(null=True, blank=True, verbose_name="Отозвана")
+
+    class Meta:
+        verbose_name = "Публичная ссылка"
+        verbose_name_plural = "Публичные ссылки"
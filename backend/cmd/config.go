@@ -0,0 +1,28 @@
+package main
+
+import (
+	"3d-backend/internal/config"
+	"github.com/jessevdk/go-flags"
+	"log"
+	"os"
+)
+
+type configPrintCommand struct {
+	cfg *config.Config
+}
+
+func addConfigCommands(parser *flags.Parser, cfg *config.Config) {
+	cmd, err := parser.AddCommand("config", "Inspect the configuration", "Show the settings the server would start with.", &struct{}{})
+	if err != nil {
+		log.Panicf("Failed to add config command: %v", err)
+	}
+
+	description := "Print the effective configuration as YAML with secrets redacted"
+	if _, err := cmd.AddCommand("print", description, description, &configPrintCommand{cfg: cfg}); err != nil {
+		log.Panicf("Failed to add config print command: %v", err)
+	}
+}
+
+func (c *configPrintCommand) Execute(args []string) error {
+	return config.Print(os.Stdout, c.cfg)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"3d-backend/internal/config"
+	"3d-backend/internal/migrate"
+	"fmt"
+	"github.com/jessevdk/go-flags"
+	"log"
+	"log/slog"
+	"time"
+)
+
+type migrateUpCommand struct {
+	cfg *config.Config
+}
+
+type migrateDownCommand struct {
+	cfg   *config.Config
+	Steps int `long:"steps" default:"1" description:"Number of migrations to revert"`
+}
+
+type migrateStatusCommand struct {
+	cfg *config.Config
+}
+
+func addMigrateCommands(parser *flags.Parser, cfg *config.Config) {
+	cmd, err := parser.AddCommand("migrate", "Manage the database schema", "Apply, revert or list the migrations embedded in the binary.", &struct{}{})
+	if err != nil {
+		log.Panicf("Failed to add migrate command: %v", err)
+	}
+
+	subcommands := []struct {
+		name, description string
+		data              interface{}
+	}{
+		{"up", "Apply all pending migrations", &migrateUpCommand{cfg: cfg}},
+		{"down", "Revert the latest migrations", &migrateDownCommand{cfg: cfg}},
+		{"status", "List migrations and whether they are applied", &migrateStatusCommand{cfg: cfg}},
+	}
+	for _, sub := range subcommands {
+		if _, err := cmd.AddCommand(sub.name, sub.description, sub.description, sub.data); err != nil {
+			log.Panicf("Failed to add migrate %s command: %v", sub.name, err)
+		}
+	}
+}
+
+func newMigrator(cfg *config.Config) (*migrate.Migrator, func(), error) {
+	if err := setupLogger(cfg.Log); err != nil {
+		return nil, nil, err
+	}
+	db, err := newPostgresDB(cfg.DB)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := migrate.NewMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return migrator, func() { db.Close() }, nil
+}
+
+func (c *migrateUpCommand) Execute(args []string) error {
+	migrator, closeDB, err := newMigrator(c.cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	applied, err := migrator.Up()
+	for _, m := range applied {
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		slog.Info("No pending migrations")
+	}
+	return nil
+}
+
+func (c *migrateDownCommand) Execute(args []string) error {
+	migrator, closeDB, err := newMigrator(c.cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	reverted, err := migrator.Down(c.Steps)
+	for _, m := range reverted {
+		slog.Info("Reverted migration", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(reverted) == 0 {
+		slog.Info("No applied migrations to revert")
+	}
+	return nil
+}
+
+func (c *migrateStatusCommand) Execute(args []string) error {
+	migrator, closeDB, err := newMigrator(c.cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		state := "pending"
+		if s.AppliedAt != nil {
+			state = "applied " + s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%04d_%-20s %s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package apperr
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Code is the machine-readable kind of an error, returned to clients next to
+// the human-readable message.
+type Code string
+
+const (
+	CodeBadRequest      Code = "bad_request"
+	CodeValidation      Code = "validation_failed"
+	CodeUnauthenticated Code = "unauthenticated"
+	CodeForbidden       Code = "forbidden"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodeRateLimited     Code = "rate_limited"
+	CodeUpstream        Code = "upstream_unavailable"
+	CodeInternal        Code = "internal"
+)
+
+var statuses = map[Code]int{
+	CodeBadRequest:      http.StatusBadRequest,
+	CodeValidation:      http.StatusUnprocessableEntity,
+	CodeUnauthenticated: http.StatusUnauthorized,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeConflict:        http.StatusConflict,
+	CodeRateLimited:     http.StatusTooManyRequests,
+	CodeUpstream:        http.StatusBadGateway,
+	CodeInternal:        http.StatusInternalServerError,
+}
+
+// Error is a domain error that knows how it is presented to clients. Fields
+// maps input field names to the rule they broke and is only set for
+// validation errors. Cause is the underlying error; it is logged but never
+// sent to clients.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  map[string]string
+	Cause   error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Code, e.Message)
+	if len(e.Fields) > 0 {
+		msg = fmt.Sprintf("%s %v", msg, e.Fields)
+	}
+	if e.Cause != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Cause)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithCause records the error that led to e, such as a failed query.
+func (e *Error) WithCause(err error) *Error {
+	e.Cause = err
+	return e
+}
+
+// Status is the HTTP status code the error is answered with.
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// BadRequest is for requests that cannot be understood at all, such as
+// malformed JSON or an unknown token.
+func BadRequest(message string) *Error {
+	return New(CodeBadRequest, message)
+}
+
+// Validation is for well-formed requests whose fields break a rule.
+func Validation(message string, fields map[string]string) *Error {
+	return &Error{Code: CodeValidation, Message: message, Fields: fields}
+}
+
+// InvalidField is a validation error about a single field.
+func InvalidField(field string, rule string, message string) *Error {
+	return Validation(message, map[string]string{field: rule})
+}
+
+func Unauthenticated(message string) *Error {
+	return New(CodeUnauthenticated, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+func RateLimited(message string) *Error {
+	return New(CodeRateLimited, message)
+}
+
+func Upstream(message string) *Error {
+	return New(CodeUpstream, message)
+}
+
+func Internal(message string) *Error {
+	return New(CodeInternal, message)
+}
@@ -0,0 +1,87 @@
+package apperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// Response is the body of every error answer. Error keeps the message under
+// the key clients already read.
+type Response struct {
+	Error  string            `json:"error" example:"Invalid input"`
+	Code   Code              `json:"code" example:"validation_failed"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func init() {
+	// Report validation failures under the JSON names clients send.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// statusClientClosedRequest is the status nginx logs for requests the client
+// abandoned. No response is sent with it.
+const statusClientClosedRequest = 499
+
+// Respond answers with err and aborts the handler chain. err is attached to
+// the context for the access log; errors that are not an *Error are hidden
+// behind a generic internal error.
+func Respond(c *gin.Context, err error) {
+	c.Error(err)
+
+	// Queries are cancelled when the client disconnects. Nobody reads the
+	// answer, and it must not count as a server error. The driver reports
+	// the cancellation as its own error, so the request context decides.
+	if errors.Is(c.Request.Context().Err(), context.Canceled) {
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		appErr = Internal("Internal server error")
+	}
+
+	c.AbortWithStatusJSON(appErr.Status(), Response{
+		Error:  appErr.Message,
+		Code:   appErr.Code,
+		Fields: appErr.Fields,
+	})
+}
+
+// Binding converts an error from ShouldBindJSON. Broken rules and values of
+// the wrong type are validation errors, anything else is a bad request.
+func Binding(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make(map[string]string, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			rule := fieldErr.Tag()
+			if fieldErr.Param() != "" {
+				rule += "=" + fieldErr.Param()
+			}
+			fields[fieldErr.Field()] = rule
+		}
+		return Validation("Invalid input", fields)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return InvalidField(typeErr.Field, "type", "Invalid input")
+	}
+
+	return BadRequest("Invalid input")
+}
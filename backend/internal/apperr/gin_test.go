@@ -0,0 +1,54 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// pq reports a cancelled statement as a server error, not as
+	// context.Canceled.
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	tests := []struct {
+		name       string
+		err        error
+		cancel     bool
+		wantStatus int
+	}{
+		{"app error", NotFound("Project not found"), false, http.StatusNotFound},
+		{"plain error", driverErr, false, http.StatusInternalServerError},
+		{"timeout", Internal("Failed").WithCause(context.DeadlineExceeded), false, http.StatusInternalServerError},
+		{"client gone", Internal("Failed").WithCause(context.Canceled), true, statusClientClosedRequest},
+		{"client gone, driver error", Internal("Failed").WithCause(driverErr), true, statusClientClosedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+			Respond(c, tt.err)
+
+			if got := c.Writer.Status(); got != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got, tt.wantStatus)
+			}
+			if len(c.Errors) != 1 || !errors.Is(c.Errors[0].Err, tt.err) {
+				t.Errorf("error not attached to the context: %v", c.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/logging"
+	"3d-backend/internal/mail"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+const minPasswordLength = 8
+
+type registerInput struct {
+	Username string `json:"username" binding:"required,max=150"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required"`
+}
+
+type registerResponse struct {
+	UserID int64 `json:"user_id"`
+}
+
+type changePasswordInput struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type requestPasswordResetInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type confirmPasswordResetInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// Register godoc
+// @Summary Регистрация
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body registerInput true "User information"
+// @Success 200 {object} registerResponse
+// @Failure 422 {object} apperr.Response "Password is too weak"
+// @Failure 409 {object} apperr.Response "Username is already taken"
+// @Router /register [post]
+func (h *Handler) Register(c *gin.Context) {
+	var input registerInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	if err := validatePassword("password", input.Password); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+
+	passwordHash, err := HashDjangoPassword(input.Password)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to hash password").WithCause(err))
+		return
+	}
+
+	userID, err := h.users.InsertUser(c.Request.Context(), input.Username, input.Email, passwordHash)
+	if errors.Is(err, ErrUsernameTaken) {
+		apperr.Respond(c, apperr.Conflict("Username is already taken"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to create user").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, registerResponse{
+		UserID: userID,
+	})
+}
+
+// ChangePassword godoc
+// @Summary Смена пароля
+// @Description Все остальные сессии пользователя завершаются.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body changePasswordInput true "Old and new password"
+// @Failure 422 {object} apperr.Response "Password is too weak"
+// @Failure 422 {object} apperr.Response "Old password is incorrect"
+// @Security BearerAuth
+// @Router /protected/change-password [post]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var input changePasswordInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	userID, err := GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	sessionID, err := GetSessionID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	usr, err := h.users.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get user").WithCause(err))
+		return
+	}
+
+	isValid, err := VerifyDjangoPassword(input.OldPassword, usr.Password)
+	if err != nil || !isValid {
+		apperr.Respond(c, apperr.InvalidField("old_password", "incorrect", "Old password is incorrect"))
+		return
+	}
+
+	if err := validatePassword("new_password", input.NewPassword); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+
+	passwordHash, err := HashDjangoPassword(input.NewPassword)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to hash password").WithCause(err))
+		return
+	}
+
+	err = h.users.UpdateUserPassword(c.Request.Context(), userID, passwordHash)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to update password").WithCause(err))
+		return
+	}
+
+	err = h.users.RevokeOtherSessions(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to revoke sessions").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// RequestPasswordReset godoc
+// @Summary Запрос сброса пароля
+// @Description Письмо со ссылкой отправляется всем активным пользователям с этим email. Ответ не раскрывает, существует ли такой пользователь.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body requestPasswordResetInput true "Email"
+// @Router /password-reset/request [post]
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	var input requestPasswordResetInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	users, err := h.users.GetActiveUsersByEmail(c.Request.Context(), input.Email)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get users").WithCause(err))
+		return
+	}
+
+	for _, usr := range users {
+		token, tokenHash, err := NewOpaqueToken()
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to generate token").WithCause(err))
+			return
+		}
+
+		err = h.users.InsertPasswordResetToken(c.Request.Context(), usr.ID, tokenHash, h.cfg.PasswordResetTokenTTL)
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to create password reset token").WithCause(err))
+			return
+		}
+
+		err = h.mailer.Send(h.passwordResetMessage(input.Email, usr.Username, token))
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("Failed to send password reset mail", "user_id", usr.ID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Установка нового пароля по токену сброса
+// @Description Все сессии пользователя завершаются.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body confirmPasswordResetInput true "Reset token and new password"
+// @Failure 400 {object} apperr.Response "Invalid or expired token"
+// @Failure 422 {object} apperr.Response "Password is too weak"
+// @Router /password-reset/confirm [post]
+func (h *Handler) ConfirmPasswordReset(c *gin.Context) {
+	var input confirmPasswordResetInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	if err := validatePassword("new_password", input.NewPassword); err != nil {
+		apperr.Respond(c, err)
+		return
+	}
+
+	passwordHash, err := HashDjangoPassword(input.NewPassword)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to hash password").WithCause(err))
+		return
+	}
+
+	_, err = h.users.ResetPassword(c.Request.Context(), HashToken(input.Token), passwordHash)
+	if errors.Is(err, ErrInvalidPasswordResetToken) {
+		apperr.Respond(c, apperr.BadRequest("Invalid or expired password reset token"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to reset password").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// validatePassword applies the length and numeric checks of Django's default
+// AUTH_PASSWORD_VALIDATORS and reports them with Django's error codes.
+func validatePassword(field string, password string) error {
+	if len([]rune(password)) < minPasswordLength {
+		return apperr.InvalidField(field, "password_too_short", fmt.Sprintf("Password must contain at least %d characters", minPasswordLength))
+	}
+	if strings.IndexFunc(password, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+		return apperr.InvalidField(field, "password_entirely_numeric", "Password can't be entirely numeric")
+	}
+	return nil
+}
+
+// passwordResetMessage links to the configured reset page when there is one
+// and falls back to the bare token otherwise.
+func (h *Handler) passwordResetMessage(email string, username string, token string) mail.Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello, %s!\n\n", username)
+	body.WriteString("Someone requested a password reset for your account. ")
+	if h.cfg.PasswordResetURL != "" {
+		fmt.Fprintf(&body, "Follow the link to set a new password:\n\n%s?token=%s\n\n", h.cfg.PasswordResetURL, token)
+	} else {
+		fmt.Fprintf(&body, "Use this token to set a new password:\n\n%s\n\n", token)
+	}
+	fmt.Fprintf(&body, "It expires in %d minutes. If you did not request a reset, ignore this email.\n", int(h.cfg.PasswordResetTokenTTL.Minutes()))
+
+	return mail.Message{
+		To:      email,
+		Subject: "Password reset",
+		Body:    body.String(),
+	}
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+// DefaultGroup is created by the accounts app migrations.
+const DefaultGroup = "Users"
+
+var (
+	ErrUsernameTaken             = errors.New("username is already taken")
+	ErrInvalidPasswordResetToken = errors.New("invalid password reset token")
+)
+
+type passwordResetTokenRow struct {
+	ID        int64      `db:"id"`
+	UserID    int64      `db:"user_id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// InsertUser creates an active, non-staff user with the same defaults as
+// Django's UserManager.create_user and adds it to DefaultGroup, which carries
+// the permissions regular users need for the project API.
+func (r *PostgresUserRepository) InsertUser(ctx context.Context, username string, email string, passwordHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	userID, err := insertUser(ctx, tx, username, email, passwordHash)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return userID, nil
+}
+
+func insertUser(ctx context.Context, tx *sqlx.Tx, username string, email string, passwordHash string) (int64, error) {
+	query := `
+		INSERT INTO auth_user (password, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined)
+		VALUES ($1, false, $2, '', '', $3, false, true, now())
+		RETURNING id;
+	`
+	var userID int64
+	err := tx.GetContext(ctx, &userID, query, passwordHash, username, email)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return 0, ErrUsernameTaken
+		}
+		return 0, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	groupQuery := `
+		INSERT INTO auth_user_groups (user_id, group_id)
+		SELECT $1, id FROM auth_group WHERE name = $2;
+	`
+	_, err = tx.ExecContext(ctx, groupQuery, userID, DefaultGroup)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add user to default group: %w", err)
+	}
+
+	return userID, nil
+}
+
+func (r *PostgresUserRepository) GetActiveUsersByEmail(ctx context.Context, email string) ([]User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	users := []User{}
+	query := `
+		SELECT ` + userColumns + `
+		FROM auth_user
+		WHERE lower(email) = lower($1) AND is_active AND email <> '';
+	`
+	err := r.db.SelectContext(ctx, &users, query, email)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *PostgresUserRepository) InsertPasswordResetToken(ctx context.Context, userID int64, tokenHash string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_passwordresettoken (user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, now(), now() + $3 * interval '1 second');
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, tokenHash, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to store password reset token: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword consumes a reset token and sets the new password. Every other
+// outstanding reset token of the user is invalidated and all sessions are
+// revoked, so a stolen session does not outlive the reset.
+func (r *PostgresUserRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, user_id, expires_at, used_at
+		FROM accounts_passwordresettoken
+		WHERE token_hash = $1
+		FOR UPDATE;
+	`
+	var row passwordResetTokenRow
+	err = tx.GetContext(ctx, &row, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidPasswordResetToken
+		}
+		return 0, err
+	}
+
+	if row.UsedAt != nil || row.ExpiresAt.Before(time.Now()) {
+		return 0, ErrInvalidPasswordResetToken
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE auth_user SET password = $1 WHERE id = $2 AND is_active`, passwordHash, row.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	tokensQuery := `
+		UPDATE accounts_passwordresettoken
+		SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL;
+	`
+	_, err = tx.ExecContext(ctx, tokensQuery, row.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark password reset token used: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE accounts_session SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`, row.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return row.UserID, nil
+}
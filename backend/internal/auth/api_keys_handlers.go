@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"3d-backend/internal/apperr"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// apiKeyPrefix marks API keys so AuthMiddleware can tell them from JWTs and
+// secret scanners can find leaked ones.
+const apiKeyPrefix = "3dk_"
+
+type createAPIKeyInput struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	ReadOnly  bool       `json:"read_only"`
+	ProjectID *int64     `json:"project_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type createAPIKeyResponse struct {
+	APIKeyID int64  `json:"api_key_id"`
+	Key      string `json:"key" example:"3dk_q8Qb0cBq1m0x7Zt3fW9pX2kLr5vN4yHs6dJ1gA0eUoI"`
+}
+
+type revokeAPIKeyInput struct {
+	APIKeyID int64 `json:"api_key_id" binding:"required"`
+}
+
+// CreateAPIKey godoc
+// @Summary Создание API-ключа
+// @Description Ключ показывается один раз. Создавать ключи можно только из сессии, не по другому API-ключу.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body createAPIKeyInput true "API key information"
+// @Success 200 {object} createAPIKeyResponse
+// @Security BearerAuth
+// @Router /protected/create-api-key [post]
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var input createAPIKeyInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	userID, err := GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	if _, err := GetSessionID(c); err != nil {
+		apperr.Respond(c, apperr.Forbidden("API keys can only be managed from a session"))
+		return
+	}
+
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now()) {
+		apperr.Respond(c, apperr.InvalidField("expires_at", "future", "Expiry must be in the future"))
+		return
+	}
+
+	token, tokenHash, err := NewOpaqueToken()
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate key").WithCause(err))
+		return
+	}
+	key := apiKeyPrefix + token
+
+	keyID, err := h.users.InsertAPIKey(c.Request.Context(), NewAPIKey{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   tokenHash,
+		ReadOnly:  input.ReadOnly,
+		ProjectID: input.ProjectID,
+		ExpiresAt: input.ExpiresAt,
+
+		MFAVerified: IsMFAVerified(c),
+	})
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to create api key").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, createAPIKeyResponse{
+		APIKeyID: keyID,
+		Key:      key,
+	})
+}
+
+// ListAPIKeys godoc
+// @Summary API-ключи текущего пользователя
+// @Tags auth
+// @Accept */*
+// @Produce json
+// @Success 200 {array} APIKey "API keys"
+// @Security BearerAuth
+// @Router /protected/api-keys [get]
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	userID, err := GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	keys, err := h.users.GetAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get api keys").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Отзыв API-ключа
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body revokeAPIKeyInput true "API key"
+// @Security BearerAuth
+// @Router /protected/revoke-api-key [post]
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	var input revokeAPIKeyInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	userID, err := GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	err = h.users.MarkAPIKeyRevoked(c.Request.Context(), input.APIKeyID, userID)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		apperr.Respond(c, apperr.NotFound("API key not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to revoke api key").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+type APIKey struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"-"`
+	Name       string     `db:"name" json:"name"`
+	Prefix     string     `db:"prefix" json:"prefix"`
+	ReadOnly   bool       `db:"read_only" json:"read_only"`
+	ProjectID  *int64     `db:"project_id" json:"project_id"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+	// MFAVerified is inherited from the session the key was created in.
+	MFAVerified bool `db:"mfa_verified" json:"-"`
+}
+
+type NewAPIKey struct {
+	UserID      int64
+	Name        string
+	Prefix      string
+	KeyHash     string
+	ReadOnly    bool
+	ProjectID   *int64
+	ExpiresAt   *time.Time
+	MFAVerified bool
+}
+
+func (r *PostgresUserRepository) InsertAPIKey(ctx context.Context, key NewAPIKey) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_apikey (user_id, name, prefix, key_hash, read_only, project_id, mfa_verified, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, now(), $8)
+		RETURNING id;
+	`
+	var keyID int64
+	err := r.db.GetContext(ctx, &keyID, query, key.UserID, key.Name, key.Prefix, key.KeyHash, key.ReadOnly, key.ProjectID, key.MFAVerified, key.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return keyID, nil
+}
+
+func (r *PostgresUserRepository) GetAPIKeys(ctx context.Context, userID int64) ([]APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	keys := []APIKey{}
+	query := `
+		SELECT id, user_id, name, prefix, read_only, project_id, mfa_verified, created_at, expires_at, last_used_at
+		FROM accounts_apikey
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC;
+	`
+	err := r.db.SelectContext(ctx, &keys, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// AuthenticateAPIKey resolves a key of an active user and records its use.
+// last_used_at is written at most once a minute to keep reads cheap.
+func (r *PostgresUserRepository) AuthenticateAPIKey(ctx context.Context, keyHash string) (APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var key APIKey
+	query := `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.read_only, k.project_id, k.mfa_verified, k.created_at, k.expires_at, k.last_used_at
+		FROM accounts_apikey k
+		JOIN auth_user u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL
+			AND (k.expires_at IS NULL OR k.expires_at > now()) AND u.is_active;
+	`
+	err := r.db.GetContext(ctx, &key, query, keyHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return key, ErrInvalidAPIKey
+		}
+		return key, err
+	}
+
+	touchQuery := `
+		UPDATE accounts_apikey
+		SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+	`
+	_, err = r.db.ExecContext(ctx, touchQuery, key.ID)
+	if err != nil {
+		return key, fmt.Errorf("failed to update api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *PostgresUserRepository) MarkAPIKeyRevoked(ctx context.Context, keyID int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE accounts_apikey
+		SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+	`
+	res, err := r.db.ExecContext(ctx, query, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"3d-backend/internal/metrics"
+	"3d-backend/internal/ratelimit"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingUserRepository fails user lookups like a database that timed out.
+type failingUserRepository struct {
+	*MemoryUserRepository
+}
+
+func (failingUserRepository) GetUserHashByUsername(ctx context.Context, username string) (User, error) {
+	return User{}, context.DeadlineExceeded
+}
+
+func signIn(r *gin.Engine, username, password string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body := `{"username": "` + username + `", "password": "` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/sign-in", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSignInErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		users      UserRepository
+		wantStatus int
+		wantCount  bool
+	}{
+		{"unknown user", NewMemoryUserRepository(), http.StatusUnauthorized, true},
+		{"repository error", failingUserRepository{NewMemoryUserRepository()}, http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := ratelimit.NewMemoryStore()
+			r := gin.New()
+			r.POST("/sign-in", NewHandler(tt.users, nil, NewSignInThrottle(store), nil, Config{}).SignIn)
+
+			for range signInUserBackoff.FreeAttempts + 1 {
+				if w := signIn(r, "nobody", "secret"); w.Code != tt.wantStatus {
+					t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+				}
+			}
+
+			blocked, err := signInUserBackoff.Check(context.Background(), store, "nobody")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counted := blocked > 0; counted != tt.wantCount {
+				t.Errorf("failures counted = %v, want %v", counted, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	if _, err := VerifyDjangoPassword("secret", dummyPasswordHash()); err != nil {
+		t.Fatalf("dummy hash does not verify: %v", err)
+	}
+	if PasswordNeedsRehash(dummyPasswordHash()) {
+		t.Error("dummy hash is not in the preferred format, so unknown users answer faster")
+	}
+}
+
+func TestSignInCountsSuccessOnlyWithTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	hash, err := HashDjangoPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := NewMemoryUserRepository()
+	users.AddUser(User{Username: "alice", Password: hash, IsActive: true}, "alice@example.com")
+	users.AddUser(User{Username: "bob", Password: hash, IsActive: false}, "bob@example.com")
+	carolID := users.AddUser(User{Username: "carol", Password: hash, IsActive: true}, "carol@example.com")
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpsertTOTPDevice(ctx, carolID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.ConfirmTOTPDevice(ctx, carolID, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(users, testKeySet(t), NewSignInThrottle(ratelimit.NewMemoryStore()), nil, Config{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, MFAChallengeTTL: time.Minute})
+	r := gin.New()
+	r.POST("/sign-in", h.SignIn)
+
+	tests := []struct {
+		username    string
+		wantStatus  int
+		wantSuccess float64
+		wantFailure float64
+	}{
+		{"alice", http.StatusOK, 1, 0},
+		{"bob", http.StatusForbidden, 0, 1},
+		{"carol", http.StatusAccepted, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			success := testutil.ToFloat64(metrics.SignIns.WithLabelValues("password", "success"))
+			failure := testutil.ToFloat64(metrics.SignIns.WithLabelValues("password", "failure"))
+
+			if w := signIn(r, tt.username, "secret"); w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if got := testutil.ToFloat64(metrics.SignIns.WithLabelValues("password", "success")) - success; got != tt.wantSuccess {
+				t.Errorf("successes counted = %v, want %v", got, tt.wantSuccess)
+			}
+			if got := testutil.ToFloat64(metrics.SignIns.WithLabelValues("password", "failure")) - failure; got != tt.wantFailure {
+				t.Errorf("failures counted = %v, want %v", got, tt.wantFailure)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"hash"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults below match the hashers shipped with Django 5.1.
+const (
+	djangoPBKDF2Iterations = 870000
+
+	djangoArgon2TimeCost    = 2
+	djangoArgon2MemoryCost  = 102400
+	djangoArgon2Parallelism = 8
+	djangoArgon2HashLength  = 16
+
+	djangoBcryptRounds = 12
+
+	djangoScryptWorkFactor  = 1 << 14
+	djangoScryptBlockSize   = 8
+	djangoScryptParallelism = 1
+	djangoScryptKeyLength   = 64
+)
+
+// preferredHasher is the first entry of Django's default PASSWORD_HASHERS.
+const preferredHasher = "pbkdf2_sha256"
+
+// passwordHasher mirrors django.contrib.auth.hashers.BasePasswordHasher.
+type passwordHasher interface {
+	Verify(password, encoded string) (bool, error)
+	Encode(password, salt string) (string, error)
+	MustUpdate(encoded string) bool
+}
+
+var passwordHashers = map[string]passwordHasher{
+	"pbkdf2_sha256": pbkdf2Hasher{algorithm: "pbkdf2_sha256", digest: sha256.New},
+	"pbkdf2_sha1":   pbkdf2Hasher{algorithm: "pbkdf2_sha1", digest: sha1.New},
+	"argon2":        argon2Hasher{},
+	"bcrypt_sha256": bcryptHasher{algorithm: "bcrypt_sha256", prehash: true},
+	"bcrypt":        bcryptHasher{algorithm: "bcrypt"},
+	"scrypt":        scryptHasher{},
+	"md5":           md5Hasher{},
+}
+
+func hasherFor(encoded string) (passwordHasher, error) {
+	algorithm, _, found := strings.Cut(encoded, "$")
+	if !found {
+		return nil, errors.New("invalid hash format")
+	}
+
+	hasher, ok := passwordHashers[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+	return hasher, nil
+}
+
+func VerifyDjangoPassword(password, djangoHash string) (bool, error) {
+	hasher, err := hasherFor(djangoHash)
+	if err != nil {
+		return false, err
+	}
+	return hasher.Verify(password, djangoHash)
+}
+
+// HashDjangoPassword produces a hash with Django's preferred hasher.
+func HashDjangoPassword(password string) (string, error) {
+	return HashDjangoPasswordWith(preferredHasher, password)
+}
+
+func HashDjangoPasswordWith(algorithm, password string) (string, error) {
+	hasher, ok := passwordHashers[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+
+	salt, err := randomString(22)
+	if err != nil {
+		return "", err
+	}
+	return hasher.Encode(password, salt)
+}
+
+// dummyPasswordHash is verified against when a user does not exist, so that
+// sign-in takes as long as for a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	encoded, err := HashDjangoPassword("dummy password")
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+})
+
+// unusablePassword mirrors Django's make_password(None): the "!" prefix never
+// matches a hasher, so the password can not be used to sign in.
+func unusablePassword() (string, error) {
+	suffix, err := randomString(40)
+	if err != nil {
+		return "", err
+	}
+	return "!" + suffix, nil
+}
+
+// PasswordNeedsRehash reports whether a verified hash should be replaced, as
+// Django does on login: either the algorithm is not the preferred one or its
+// work factor is below the current default.
+func PasswordNeedsRehash(djangoHash string) bool {
+	algorithm, _, _ := strings.Cut(djangoHash, "$")
+	if algorithm != preferredHasher {
+		return true
+	}
+
+	hasher, err := hasherFor(djangoHash)
+	if err != nil {
+		return false
+	}
+	return hasher.MustUpdate(djangoHash)
+}
+
+type pbkdf2Hasher struct {
+	algorithm string
+	digest    func() hash.Hash
+}
+
+func (h pbkdf2Hasher) Verify(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != h.algorithm {
+		return false, errors.New("invalid hash format")
+	}
+
+	iterStr, salt, expectedHash := parts[1], parts[2], parts[3]
+
+	iterations, err := strconv.Atoi(iterStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid iteration count: %s", iterStr)
+	}
+
+	expectedHashBytes, err := base64.StdEncoding.DecodeString(expectedHash)
+	if err != nil {
+		return false, fmt.Errorf("invalid hash encoding: %v", err)
+	}
+
+	derivedKey := pbkdf2.Key([]byte(password), []byte(salt), iterations, len(expectedHashBytes), h.digest)
+
+	return hmac.Equal(derivedKey, expectedHashBytes), nil
+}
+
+func (h pbkdf2Hasher) Encode(password, salt string) (string, error) {
+	derivedKey := pbkdf2.Key([]byte(password), []byte(salt), djangoPBKDF2Iterations, h.digest().Size(), h.digest)
+	encodedHash := base64.StdEncoding.EncodeToString(derivedKey)
+
+	return fmt.Sprintf("%s$%d$%s$%s", h.algorithm, djangoPBKDF2Iterations, salt, encodedHash), nil
+}
+
+func (h pbkdf2Hasher) MustUpdate(encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	return err == nil && iterations < djangoPBKDF2Iterations
+}
+
+// argon2Hasher handles "argon2$argon2id$v=19$m=102400,t=2,p=8$salt$hash".
+// Only the argon2i and argon2id variants at version 19 are supported.
+type argon2Hasher struct{}
+
+type argon2Params struct {
+	variant     string
+	memory      uint32
+	time        uint32
+	parallelism uint8
+	salt        []byte
+	hash        []byte
+}
+
+func parseArgon2(encoded string) (argon2Params, error) {
+	var params argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "argon2" {
+		return params, errors.New("invalid hash format")
+	}
+
+	params.variant = parts[1]
+	if parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return params, fmt.Errorf("unsupported argon2 version: %s", parts[2])
+	}
+
+	var parallelism uint32
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &parallelism)
+	if err != nil {
+		return params, fmt.Errorf("invalid argon2 parameters: %s", parts[3])
+	}
+	params.parallelism = uint8(parallelism)
+
+	params.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, fmt.Errorf("invalid salt encoding: %v", err)
+	}
+	params.hash, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, fmt.Errorf("invalid hash encoding: %v", err)
+	}
+
+	return params, nil
+}
+
+func (argon2Hasher) Verify(password, encoded string) (bool, error) {
+	params, err := parseArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	keyLength := uint32(len(params.hash))
+	var derivedKey []byte
+	switch params.variant {
+	case "argon2id":
+		derivedKey = argon2.IDKey([]byte(password), params.salt, params.time, params.memory, params.parallelism, keyLength)
+	case "argon2i":
+		derivedKey = argon2.Key([]byte(password), params.salt, params.time, params.memory, params.parallelism, keyLength)
+	default:
+		return false, fmt.Errorf("unsupported argon2 variant: %s", params.variant)
+	}
+
+	return subtle.ConstantTimeCompare(derivedKey, params.hash) == 1, nil
+}
+
+func (argon2Hasher) Encode(password, salt string) (string, error) {
+	derivedKey := argon2.IDKey([]byte(password), []byte(salt), djangoArgon2TimeCost, djangoArgon2MemoryCost, djangoArgon2Parallelism, djangoArgon2HashLength)
+
+	return fmt.Sprintf("argon2$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, djangoArgon2MemoryCost, djangoArgon2TimeCost, djangoArgon2Parallelism,
+		base64.RawStdEncoding.EncodeToString([]byte(salt)),
+		base64.RawStdEncoding.EncodeToString(derivedKey),
+	), nil
+}
+
+func (argon2Hasher) MustUpdate(encoded string) bool {
+	params, err := parseArgon2(encoded)
+	if err != nil {
+		return false
+	}
+	return params.variant != "argon2id" ||
+		params.time != djangoArgon2TimeCost ||
+		params.memory != djangoArgon2MemoryCost ||
+		params.parallelism != djangoArgon2Parallelism
+}
+
+// bcryptHasher handles "bcrypt$$2b$12$..." and "bcrypt_sha256$$2b$12$...".
+// The sha256 variant feeds the hex digest of the password to bcrypt so that
+// passwords longer than 72 bytes are not truncated.
+type bcryptHasher struct {
+	algorithm string
+	prehash   bool
+}
+
+func (h bcryptHasher) secret(password string) []byte {
+	if !h.prehash {
+		return []byte(password)
+	}
+	sum := sha256.Sum256([]byte(password))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+func (h bcryptHasher) Verify(password, encoded string) (bool, error) {
+	data, found := strings.CutPrefix(encoded, h.algorithm+"$")
+	if !found {
+		return false, errors.New("invalid hash format")
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(data), h.secret(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Encode ignores salt: bcrypt generates its own.
+func (h bcryptHasher) Encode(password, _ string) (string, error) {
+	data, err := bcrypt.GenerateFromPassword(h.secret(password), djangoBcryptRounds)
+	if err != nil {
+		return "", err
+	}
+	return h.algorithm + "$" + string(data), nil
+}
+
+func (h bcryptHasher) MustUpdate(encoded string) bool {
+	data, _ := strings.CutPrefix(encoded, h.algorithm+"$")
+	cost, err := bcrypt.Cost([]byte(data))
+	return err == nil && cost != djangoBcryptRounds
+}
+
+// scryptHasher handles "scrypt$salt$n$r$p$hash".
+type scryptHasher struct{}
+
+type scryptParams struct {
+	salt        string
+	workFactor  int
+	blockSize   int
+	parallelism int
+	hash        []byte
+}
+
+func parseScrypt(encoded string) (scryptParams, error) {
+	var params scryptParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "scrypt" {
+		return params, errors.New("invalid hash format")
+	}
+
+	params.salt = parts[1]
+	var err error
+	if params.workFactor, err = strconv.Atoi(parts[2]); err != nil {
+		return params, fmt.Errorf("invalid work factor: %s", parts[2])
+	}
+	if params.blockSize, err = strconv.Atoi(parts[3]); err != nil {
+		return params, fmt.Errorf("invalid block size: %s", parts[3])
+	}
+	if params.parallelism, err = strconv.Atoi(parts[4]); err != nil {
+		return params, fmt.Errorf("invalid parallelism: %s", parts[4])
+	}
+	if params.hash, err = base64.StdEncoding.DecodeString(parts[5]); err != nil {
+		return params, fmt.Errorf("invalid hash encoding: %v", err)
+	}
+
+	return params, nil
+}
+
+func (scryptHasher) Verify(password, encoded string) (bool, error) {
+	params, err := parseScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	derivedKey, err := scrypt.Key([]byte(password), []byte(params.salt), params.workFactor, params.blockSize, params.parallelism, len(params.hash))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(derivedKey, params.hash) == 1, nil
+}
+
+func (scryptHasher) Encode(password, salt string) (string, error) {
+	derivedKey, err := scrypt.Key([]byte(password), []byte(salt), djangoScryptWorkFactor, djangoScryptBlockSize, djangoScryptParallelism, djangoScryptKeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("scrypt$%s$%d$%d$%d$%s",
+		salt, djangoScryptWorkFactor, djangoScryptBlockSize, djangoScryptParallelism,
+		base64.StdEncoding.EncodeToString(derivedKey),
+	), nil
+}
+
+func (scryptHasher) MustUpdate(encoded string) bool {
+	params, err := parseScrypt(encoded)
+	if err != nil {
+		return false
+	}
+	return params.workFactor != djangoScryptWorkFactor ||
+		params.blockSize != djangoScryptBlockSize ||
+		params.parallelism != djangoScryptParallelism
+}
+
+// md5Hasher handles the legacy salted "md5$salt$hexdigest" format. Django keeps
+// it for tests only, so hashes in this format are always upgraded on login.
+type md5Hasher struct{}
+
+func (md5Hasher) Verify(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 3 || parts[0] != "md5" {
+		return false, errors.New("invalid hash format")
+	}
+
+	sum := md5.Sum([]byte(parts[1] + password))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(parts[2])) == 1, nil
+}
+
+func (md5Hasher) Encode(password, salt string) (string, error) {
+	sum := md5.Sum([]byte(salt + password))
+	return fmt.Sprintf("md5$%s$%s", salt, hex.EncodeToString(sum[:])), nil
+}
+
+func (md5Hasher) MustUpdate(string) bool {
+	return false
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// Hashes below are what Django 5.1 returns from make_password(password, salt,
+// hasher), with salt "seasalt" or, for bcrypt, a fixed bcrypt.gensalt().
+var djangoHashes = []struct {
+	name        string
+	password    string
+	encoded     string
+	mustUpdate  bool
+	needsRehash bool
+}{
+	{
+		name:     "pbkdf2_sha256",
+		password: "lètmein",
+		encoded:  "pbkdf2_sha256$870000$seasalt$wJSpLMQRQz0Dhj/pFpbyjMj71B2gUYp6HJS5AU+32Ac=",
+	},
+	{
+		name:        "pbkdf2_sha256 with Django 4.2 iterations",
+		password:    "lètmein",
+		encoded:     "pbkdf2_sha256$600000$seasalt$OAXyhAQ/4ZDA9V5RMExt3C1OwQdUpLZ99vm1McFlLRA=",
+		mustUpdate:  true,
+		needsRehash: true,
+	},
+	{
+		name:        "pbkdf2_sha1",
+		password:    "lètmein",
+		encoded:     "pbkdf2_sha1$870000$seasalt$UiqYIlBUWaJY1625aFAwcLy17so=",
+		needsRehash: true,
+	},
+	{
+		// Django's own argon2i fixture, as written by older argon2-cffi.
+		name:        "argon2i",
+		password:    "secret",
+		encoded:     "argon2$argon2i$v=19$m=8,t=1,p=1$c2FsdHNhbHQ$YC9+jJCrQhs5R6db7LlN8Q",
+		mustUpdate:  true,
+		needsRehash: true,
+	},
+	{
+		name:        "bcrypt_sha256",
+		password:    "lètmein",
+		encoded:     "bcrypt_sha256$$2b$12$abcdefghijklmnopqrstuuVrQ4zCyEDfwKOXCre954in7jn/y/.ua",
+		needsRehash: true,
+	},
+	{
+		name:        "bcrypt",
+		password:    "lètmein",
+		encoded:     "bcrypt$$2b$12$abcdefghijklmnopqrstuupd4kvGe1RE7cUAJBlAgNLjj8dnLUqIu",
+		needsRehash: true,
+	},
+	{
+		name:        "bcrypt with 4 rounds",
+		password:    "lètmein",
+		encoded:     "bcrypt$$2b$04$abcdefghijklmnopqrstuuanVo7Xut1CH8VGIGlz1JovQh9GJbbtG",
+		mustUpdate:  true,
+		needsRehash: true,
+	},
+	{
+		name:        "scrypt",
+		password:    "lètmein",
+		encoded:     "scrypt$seasalt$16384$8$1$Qj3+9PPyRjSJIebHnG81TMjsqtaIGxNQG/aEB/NYafTJ7tibgfYz71m0ldQESkXFRkdVCBhhY8mx7rQwite/Pw==",
+		needsRehash: true,
+	},
+	{
+		name:        "md5",
+		password:    "lètmein",
+		encoded:     "md5$seasalt$3f86d0d3d465b7b458c231bf3555c0e3",
+		needsRehash: true,
+	},
+}
+
+func TestVerifyDjangoPassword(t *testing.T) {
+	for _, tt := range djangoHashes {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyDjangoPassword(tt.password, tt.encoded)
+			if err != nil || !ok {
+				t.Fatalf("VerifyDjangoPassword(%q) = %v, %v, want true", tt.password, ok, err)
+			}
+
+			ok, err = VerifyDjangoPassword("wrong", tt.encoded)
+			if err != nil || ok {
+				t.Fatalf("VerifyDjangoPassword(wrong) = %v, %v, want false", ok, err)
+			}
+
+			hasher, err := hasherFor(tt.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hasher.MustUpdate(tt.encoded); got != tt.mustUpdate {
+				t.Errorf("MustUpdate = %v, want %v", got, tt.mustUpdate)
+			}
+			if got := PasswordNeedsRehash(tt.encoded); got != tt.needsRehash {
+				t.Errorf("PasswordNeedsRehash = %v, want %v", got, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestEncodeMatchesDjango(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"pbkdf2_sha256", "pbkdf2_sha256$870000$seasalt$wJSpLMQRQz0Dhj/pFpbyjMj71B2gUYp6HJS5AU+32Ac="},
+		{"pbkdf2_sha1", "pbkdf2_sha1$870000$seasalt$UiqYIlBUWaJY1625aFAwcLy17so="},
+		{"scrypt", "scrypt$seasalt$16384$8$1$Qj3+9PPyRjSJIebHnG81TMjsqtaIGxNQG/aEB/NYafTJ7tibgfYz71m0ldQESkXFRkdVCBhhY8mx7rQwite/Pw=="},
+		{"md5", "md5$seasalt$3f86d0d3d465b7b458c231bf3555c0e3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			got, err := passwordHashers[tt.algorithm].Encode("lètmein", "seasalt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Encode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashDjangoPasswordWith(t *testing.T) {
+	for algorithm, hasher := range passwordHashers {
+		t.Run(algorithm, func(t *testing.T) {
+			encoded, err := HashDjangoPasswordWith(algorithm, "lètmein")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(encoded, algorithm+"$") {
+				t.Fatalf("encoded = %q, want %s prefix", encoded, algorithm)
+			}
+
+			ok, err := VerifyDjangoPassword("lètmein", encoded)
+			if err != nil || !ok {
+				t.Fatalf("VerifyDjangoPassword = %v, %v, want true", ok, err)
+			}
+			if hasher.MustUpdate(encoded) {
+				t.Error("MustUpdate = true for a hash with the current defaults")
+			}
+		})
+	}
+}
+
+func TestArgon2HashLength(t *testing.T) {
+	encoded, err := argon2Hasher{}.Encode("lètmein", "seasaltseasalt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := parseArgon2(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params.hash) != djangoArgon2HashLength {
+		t.Errorf("hash length = %d, want %d", len(params.hash), djangoArgon2HashLength)
+	}
+}
+
+func TestUnusablePassword(t *testing.T) {
+	encoded, err := unusablePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyDjangoPassword("", encoded); err == nil {
+		t.Error("unusable password verified without an error")
+	}
+}
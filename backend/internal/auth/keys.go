@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// signingKey is one entry of the key set. Private is nil for retired keys that
+// are kept only to verify tokens issued before a rotation.
+type signingKey struct {
+	KID     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// activeKIDFile in the key directory names the signing key when no kid is
+// configured explicitly, so the active key can change on Reload.
+const activeKIDFile = "active"
+
+// KeySet holds the keys tokens are signed and verified with. Every *.pem file
+// in the key directory is a key whose kid is the file name without extension.
+// Rotation is: add the new key file, Reload so it appears in the JWKS, write
+// its kid to the active file and Reload again, then delete the old file once
+// the tokens it signed have expired.
+type KeySet struct {
+	dir       string
+	activeKID string
+
+	mu     sync.RWMutex
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+type JWK struct {
+	KTY string `json:"kty"`
+	KID string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the keys from dir. activeKID may be empty when the
+// directory has an active file or holds exactly one private key.
+func LoadKeySet(dir string, activeKID string) (*KeySet, error) {
+	ks := &KeySet{dir: dir, activeKID: activeKID}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload re-reads the key directory. On error the current keys stay in use.
+func (ks *KeySet) Reload() error {
+	if ks.dir == "" {
+		return errors.New("no JWT key directory configured")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return fmt.Errorf("failed to list JWT keys: %w", err)
+	}
+
+	keys := make(map[string]*signingKey, len(paths))
+	var private []string
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := readKey(path, kid)
+		if err != nil {
+			return err
+		}
+		keys[kid] = key
+		if key.Private != nil {
+			private = append(private, kid)
+		}
+	}
+
+	activeKID := ks.activeKID
+	if activeKID == "" {
+		data, err := os.ReadFile(filepath.Join(ks.dir, activeKIDFile))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read active JWT kid: %w", err)
+		}
+		activeKID = strings.TrimSpace(string(data))
+	}
+	if activeKID == "" {
+		if len(private) != 1 {
+			return fmt.Errorf("expected exactly one private JWT key in %s when no active kid is set, found %d", ks.dir, len(private))
+		}
+		activeKID = private[0]
+	}
+
+	active, ok := keys[activeKID]
+	if !ok || active.Private == nil {
+		return fmt.Errorf("no private JWT key with kid %q in %s", activeKID, ks.dir)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.active = active
+	ks.keys = keys
+	return nil
+}
+
+func readKey(path string, kid string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key %s: %w", kid, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key %s is not PEM encoded", kid)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("JWT key %s has unsupported PEM type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %s: %w", kid, err)
+	}
+
+	key := &signingKey{KID: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		key.Public = signer.Public()
+	} else {
+		key.Public = parsed
+	}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA JWT key %s is shorter than 2048 bits", kid)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("JWT key %s must be RSA or Ed25519, got %T", kid, key.Public)
+	}
+
+	return key, nil
+}
+
+// Sign signs the claims with the active key and records its kid in the header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	active := ks.active
+	ks.mu.RUnlock()
+
+	token := jwt.NewWithClaims(active.Method, claims)
+	token.Header["kid"] = active.KID
+	return token.SignedString(active.Private)
+}
+
+// Keyfunc resolves the verification key by kid for jwt.Parse.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// JWKS returns the public halves of all keys, ordered by kid.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk := JWK{KID: key.KID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KTY = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KTY = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KID < set.Keys[j].KID })
+	return set
+}
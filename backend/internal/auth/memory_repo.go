@@ -0,0 +1,661 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryUser struct {
+	User
+	Email       string
+	Groups      []string
+	Permissions []string
+}
+
+type memoryResetToken struct {
+	UserID    int64
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type memoryRefreshToken struct {
+	SessionID int64
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type memorySession struct {
+	Session
+	MFAVerified bool
+}
+
+type memoryAPIKey struct {
+	APIKey
+	KeyHash   string
+	RevokedAt *time.Time
+}
+
+type memoryMFAChallenge struct {
+	UserID    int64
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type memoryOIDCIdentity struct {
+	Issuer  string
+	Subject string
+}
+
+// MemoryUserRepository is a UserRepository that keeps everything in maps
+// behind a mutex. It follows the rules of PostgresUserRepository and is meant
+// for tests, which seed it with AddUser and SetGroupPermissions.
+type MemoryUserRepository struct {
+	mu               sync.Mutex
+	nextID           int64
+	users            map[int64]*memoryUser
+	groupPermissions map[string][]string
+	resetTokens      map[string]*memoryResetToken
+	sessions         map[int64]*memorySession
+	refreshTokens    map[string]*memoryRefreshToken
+	apiKeys          map[int64]*memoryAPIKey
+	totpDevices      map[int64]*TOTPDevice
+	recoveryCodes    map[int64]map[string]bool
+	mfaChallenges    map[string]*memoryMFAChallenge
+	oidcStates       map[string]OIDCLoginState
+	oidcIdentities   map[memoryOIDCIdentity]int64
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:            map[int64]*memoryUser{},
+		groupPermissions: map[string][]string{},
+		resetTokens:      map[string]*memoryResetToken{},
+		sessions:         map[int64]*memorySession{},
+		refreshTokens:    map[string]*memoryRefreshToken{},
+		apiKeys:          map[int64]*memoryAPIKey{},
+		totpDevices:      map[int64]*TOTPDevice{},
+		recoveryCodes:    map[int64]map[string]bool{},
+		mfaChallenges:    map[string]*memoryMFAChallenge{},
+		oidcStates:       map[string]OIDCLoginState{},
+		oidcIdentities:   map[memoryOIDCIdentity]int64{},
+	}
+}
+
+// AddUser stores usr as is, including its Groups, and returns its ID. A zero
+// ID is replaced with the next free one.
+func (r *MemoryUserRepository) AddUser(usr User, email string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if usr.ID == 0 {
+		usr.ID = r.newID()
+	} else if usr.ID > r.nextID {
+		r.nextID = usr.ID
+	}
+	r.users[usr.ID] = &memoryUser{User: usr, Email: email, Groups: slices.Clone(usr.Groups)}
+	return usr.ID
+}
+
+// SetGroupPermissions replaces the permissions of a group, e.g. DefaultGroup.
+func (r *MemoryUserRepository) SetGroupPermissions(group string, perms ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.groupPermissions[group] = perms
+}
+
+// SetUserPermissions replaces the permissions granted to the user directly.
+func (r *MemoryUserRepository) SetUserPermissions(userID int64, perms ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if usr, ok := r.users[userID]; ok {
+		usr.Permissions = perms
+	}
+}
+
+func (r *MemoryUserRepository) newID() int64 {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *MemoryUserRepository) GetUserHashByUsername(ctx context.Context, username string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, usr := range r.users {
+		if usr.Username == username {
+			return usr.User, nil
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+func (r *MemoryUserRepository) GetUserByID(ctx context.Context, userID int64) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usr, ok := r.users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return usr.User, nil
+}
+
+func (r *MemoryUserRepository) IsStaffUser(ctx context.Context, userID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usr, ok := r.users[userID]
+	if !ok {
+		return false, ErrUserNotFound
+	}
+	return usr.IsActive && (usr.IsStaff || usr.IsSuperuser), nil
+}
+
+func (r *MemoryUserRepository) UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if usr, ok := r.users[userID]; ok {
+		usr.Password = passwordHash
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) InsertUser(ctx context.Context, username string, email string, passwordHash string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.insertUser(username, email, passwordHash)
+}
+
+func (r *MemoryUserRepository) insertUser(username string, email string, passwordHash string) (int64, error) {
+	for _, usr := range r.users {
+		if usr.Username == username {
+			return 0, ErrUsernameTaken
+		}
+	}
+
+	userID := r.newID()
+	r.users[userID] = &memoryUser{
+		User: User{
+			ID:       userID,
+			Username: username,
+			Password: passwordHash,
+			IsActive: true,
+		},
+		Email:  email,
+		Groups: []string{DefaultGroup},
+	}
+	return userID, nil
+}
+
+func (r *MemoryUserRepository) GetActiveUsersByEmail(ctx context.Context, email string) ([]User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := []User{}
+	for _, usr := range r.users {
+		if usr.IsActive && usr.Email != "" && strings.EqualFold(usr.Email, email) {
+			users = append(users, usr.User)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r *MemoryUserRepository) GetUserGroups(ctx context.Context, userID int64) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := []string{}
+	if usr, ok := r.users[userID]; ok {
+		groups = append(groups, usr.Groups...)
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+func (r *MemoryUserRepository) HasPermission(ctx context.Context, userID int64, perm string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usr, ok := r.users[userID]
+	if !ok || !usr.IsActive {
+		return false, nil
+	}
+	if usr.IsSuperuser || slices.Contains(usr.Permissions, perm) {
+		return true, nil
+	}
+	for _, group := range usr.Groups {
+		if slices.Contains(r.groupPermissions[group], perm) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryUserRepository) InsertPasswordResetToken(ctx context.Context, userID int64, tokenHash string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resetTokens[tokenHash] = &memoryResetToken{
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.resetTokens[tokenHash]
+	now := time.Now()
+	if !ok || token.UsedAt != nil || token.ExpiresAt.Before(now) {
+		return 0, ErrInvalidPasswordResetToken
+	}
+
+	if usr, ok := r.users[token.UserID]; ok && usr.IsActive {
+		usr.Password = passwordHash
+	}
+
+	for _, other := range r.resetTokens {
+		if other.UserID == token.UserID && other.UsedAt == nil {
+			other.UsedAt = &now
+		}
+	}
+
+	for _, session := range r.sessions {
+		if session.UserID == token.UserID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+
+	return token.UserID, nil
+}
+
+func (r *MemoryUserRepository) InsertSession(ctx context.Context, userID int64, userAgent string, ipAddress string, refreshTokenHash string, mfaVerified bool, ttl time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	sessionID := r.newID()
+	r.sessions[sessionID] = &memorySession{
+		Session: Session{
+			ID:         sessionID,
+			UserID:     userID,
+			UserAgent:  userAgent,
+			IPAddress:  ipAddress,
+			CreatedAt:  now,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(ttl),
+		},
+		MFAVerified: mfaVerified,
+	}
+	r.refreshTokens[refreshTokenHash] = &memoryRefreshToken{
+		SessionID: sessionID,
+		ExpiresAt: now.Add(ttl),
+	}
+	return sessionID, nil
+}
+
+func (r *MemoryUserRepository) RotateRefreshToken(ctx context.Context, tokenHash string, newTokenHash string, ttl time.Duration) (int64, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.refreshTokens[tokenHash]
+	if !ok {
+		return 0, 0, ErrInvalidRefreshToken
+	}
+	session := r.sessions[token.SessionID]
+
+	now := time.Now()
+	if token.UsedAt != nil {
+		if session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+		return 0, 0, ErrRefreshTokenReused
+	}
+
+	if session.RevokedAt != nil || token.ExpiresAt.Before(now) || session.ExpiresAt.Before(now) {
+		return 0, 0, ErrInvalidRefreshToken
+	}
+
+	token.UsedAt = &now
+	r.refreshTokens[newTokenHash] = &memoryRefreshToken{
+		SessionID: session.ID,
+		ExpiresAt: now.Add(ttl),
+	}
+	session.LastUsedAt = now
+
+	return session.UserID, session.ID, nil
+}
+
+func (r *MemoryUserRepository) GetSessionState(ctx context.Context, sessionID int64, userID int64) (SessionState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return SessionState{}, nil
+	}
+	usr, ok := r.users[userID]
+	return SessionState{
+		Active:      ok && usr.IsActive && session.RevokedAt == nil && session.ExpiresAt.After(time.Now()),
+		MFAVerified: session.MFAVerified,
+	}, nil
+}
+
+func (r *MemoryUserRepository) GetActiveSessions(ctx context.Context, userID int64) ([]Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	sessions := []Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session.Session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions, nil
+}
+
+func (r *MemoryUserRepository) MarkSessionRevoked(ctx context.Context, sessionID int64, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (r *MemoryUserRepository) RevokeOtherSessions(ctx context.Context, userID int64, currentSessionID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.ID != currentSessionID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) InsertAPIKey(ctx context.Context, key NewAPIKey) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keyID := r.newID()
+	r.apiKeys[keyID] = &memoryAPIKey{
+		APIKey: APIKey{
+			ID:          keyID,
+			UserID:      key.UserID,
+			Name:        key.Name,
+			Prefix:      key.Prefix,
+			ReadOnly:    key.ReadOnly,
+			ProjectID:   key.ProjectID,
+			CreatedAt:   time.Now(),
+			ExpiresAt:   key.ExpiresAt,
+			MFAVerified: key.MFAVerified,
+		},
+		KeyHash: key.KeyHash,
+	}
+	return keyID, nil
+}
+
+func (r *MemoryUserRepository) GetAPIKeys(ctx context.Context, userID int64) ([]APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := []APIKey{}
+	for _, key := range r.apiKeys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			keys = append(keys, key.APIKey)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID > keys[j].ID })
+	return keys, nil
+}
+
+func (r *MemoryUserRepository) AuthenticateAPIKey(ctx context.Context, keyHash string) (APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range r.apiKeys {
+		if key.KeyHash != keyHash || key.RevokedAt != nil {
+			continue
+		}
+		if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+			break
+		}
+		if usr, ok := r.users[key.UserID]; !ok || !usr.IsActive {
+			break
+		}
+
+		found := key.APIKey
+		if key.LastUsedAt == nil || key.LastUsedAt.Before(now.Add(-time.Minute)) {
+			key.LastUsedAt = &now
+		}
+		return found, nil
+	}
+	return APIKey{}, ErrInvalidAPIKey
+}
+
+func (r *MemoryUserRepository) MarkAPIKeyRevoked(ctx context.Context, keyID int64, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[keyID]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return ErrAPIKeyNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return nil
+}
+
+func (r *MemoryUserRepository) GetTOTPDevice(ctx context.Context, userID int64) (TOTPDevice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, ok := r.totpDevices[userID]
+	if !ok {
+		return TOTPDevice{}, ErrTOTPNotEnrolled
+	}
+	return *device, nil
+}
+
+func (r *MemoryUserRepository) HasConfirmedTOTP(ctx context.Context, userID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, ok := r.totpDevices[userID]
+	return ok && device.ConfirmedAt != nil, nil
+}
+
+func (r *MemoryUserRepository) UpsertTOTPDevice(ctx context.Context, userID int64, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if device, ok := r.totpDevices[userID]; ok && device.ConfirmedAt != nil {
+		return ErrTOTPAlreadyConfirmed
+	}
+	r.totpDevices[userID] = &TOTPDevice{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (r *MemoryUserRepository) ConfirmTOTPDevice(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, ok := r.totpDevices[userID]
+	if !ok || device.ConfirmedAt != nil {
+		return ErrTOTPAlreadyConfirmed
+	}
+	now := time.Now()
+	device.ConfirmedAt = &now
+	device.LastUsedStep = step
+
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, codeHash := range recoveryCodeHashes {
+		codes[codeHash] = false
+	}
+	r.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (r *MemoryUserRepository) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, ok := r.totpDevices[userID]
+	if !ok || device.ConfirmedAt == nil || device.LastUsedStep >= step {
+		return false, nil
+	}
+	device.LastUsedStep = step
+	return true, nil
+}
+
+func (r *MemoryUserRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, ok := r.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (r *MemoryUserRepository) DeleteTOTPDevice(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.recoveryCodes, userID)
+	delete(r.totpDevices, userID)
+	return nil
+}
+
+func (r *MemoryUserRepository) InsertMFAChallenge(ctx context.Context, userID int64, tokenHash string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mfaChallenges[tokenHash] = &memoryMFAChallenge{
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) GetMFAChallengeUser(ctx context.Context, tokenHash string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.mfaChallenges[tokenHash]
+	if !ok || challenge.UsedAt != nil || !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= maxMFAAttempts {
+		return 0, ErrInvalidMFAChallenge
+	}
+	return challenge.UserID, nil
+}
+
+func (r *MemoryUserRepository) AttemptMFAChallenge(ctx context.Context, tokenHash string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.mfaChallenges[tokenHash]
+	if !ok || challenge.UsedAt != nil || !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= maxMFAAttempts {
+		return 0, ErrInvalidMFAChallenge
+	}
+	challenge.Attempts++
+	return challenge.UserID, nil
+}
+
+func (r *MemoryUserRepository) MarkMFAChallengeUsed(ctx context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if challenge, ok := r.mfaChallenges[tokenHash]; ok {
+		now := time.Now()
+		challenge.UsedAt = &now
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) InsertOIDCLoginState(ctx context.Context, stateHash string, codeVerifier string, nonce string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.oidcStates[stateHash] = OIDCLoginState{
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (OIDCLoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.oidcStates[stateHash]
+	if !ok {
+		return state, ErrInvalidOIDCState
+	}
+	delete(r.oidcStates, stateHash)
+	if state.ExpiresAt.Before(time.Now()) {
+		return state, ErrInvalidOIDCState
+	}
+	return state, nil
+}
+
+func (r *MemoryUserRepository) GetUserByOIDCIdentity(ctx context.Context, issuer string, subject string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, ok := r.oidcIdentities[memoryOIDCIdentity{Issuer: issuer, Subject: subject}]
+	if !ok {
+		return User{}, ErrOIDCIdentityNotFound
+	}
+	return r.users[userID].User, nil
+}
+
+func (r *MemoryUserRepository) InsertOIDCIdentity(ctx context.Context, issuer string, subject string, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.oidcIdentities[memoryOIDCIdentity{Issuer: issuer, Subject: subject}] = userID
+	return nil
+}
+
+func (r *MemoryUserRepository) InsertOIDCUser(ctx context.Context, issuer string, subject string, username string, email string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	password, err := unusablePassword()
+	if err != nil {
+		return 0, err
+	}
+
+	userID, err := r.insertUser(username, email, password)
+	if err != nil {
+		return 0, err
+	}
+
+	r.oidcIdentities[memoryOIDCIdentity{Issuer: issuer, Subject: subject}] = userID
+	return userID, nil
+}
@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/metrics"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type mfaChallengeResponse struct {
+	MFARequired    bool   `json:"mfa_required" example:"true"`
+	ChallengeToken string `json:"challenge_token" example:"q8Qb0cBq1m0x7Zt3fW9pX2kLr5vN4yHs6dJ1gA0eUoI"`
+	ExpiresIn      int64  `json:"expires_in" example:"300"`
+}
+
+type verifyMFAInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required" example:"123456"`
+}
+
+type enrollTOTPResponse struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/3d-backend:alice?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=3d-backend"`
+}
+
+type totpCodeInput struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"abcd-efgh,jkmn-pqrs"`
+}
+
+// VerifyMFA godoc
+// @Summary Второй шаг входа: код из приложения или код восстановления
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body verifyMFAInput true "Challenge token and code"
+// @Success 200 {object} signInResponse "JWT token"
+// @Failure 401 {object} apperr.Response "Invalid code or expired challenge"
+// @Failure 429 {object} apperr.Response "Too many failed attempts"
+// @Router /verify-2fa [post]
+func (h *Handler) VerifyMFA(c *gin.Context) {
+	var input verifyMFAInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	// The challenge is looked up without counting an attempt so that the
+	// throttle of its user applies before the code is checked: otherwise a
+	// known password buys fresh challenges to guess the code with.
+	challengeHash := HashToken(input.ChallengeToken)
+	userID, err := h.users.GetMFAChallengeUser(c.Request.Context(), challengeHash)
+	if errors.Is(err, ErrInvalidMFAChallenge) {
+		metrics.SignIns.WithLabelValues("totp", "failure").Inc()
+		apperr.Respond(c, apperr.Unauthenticated("Invalid or expired challenge"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to check challenge").WithCause(err))
+		return
+	}
+
+	usr, err := h.users.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get user").WithCause(err))
+		return
+	}
+
+	if !h.throttle.allowed(c, "totp", usr.Username) {
+		return
+	}
+
+	_, err = h.users.AttemptMFAChallenge(c.Request.Context(), challengeHash)
+	if errors.Is(err, ErrInvalidMFAChallenge) {
+		metrics.SignIns.WithLabelValues("totp", "failure").Inc()
+		apperr.Respond(c, apperr.Unauthenticated("Invalid or expired challenge"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to check challenge").WithCause(err))
+		return
+	}
+
+	valid, err := h.checkSecondFactor(c.Request.Context(), userID, input.Code)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to check code").WithCause(err))
+		return
+	}
+	if !valid {
+		h.throttle.recordFailure(c, "totp", usr.Username)
+		apperr.Respond(c, apperr.Unauthenticated("Invalid code"))
+		return
+	}
+
+	err = h.users.MarkMFAChallengeUsed(c.Request.Context(), challengeHash)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to use challenge").WithCause(err))
+		return
+	}
+
+	if !usr.IsActive {
+		metrics.SignIns.WithLabelValues("totp", "failure").Inc()
+		apperr.Respond(c, apperr.Forbidden("User account is disabled"))
+		return
+	}
+
+	h.startSession(c, usr, "totp", true)
+}
+
+// EnrollTOTP godoc
+// @Summary Начало подключения двухфакторной аутентификации
+// @Description Возвращает секрет и otpauth:// URI для QR-кода. 2FA включается после подтверждения кодом.
+// @Tags auth
+// @Accept */*
+// @Produce json
+// @Success 200 {object} enrollTOTPResponse
+// @Failure 409 {object} apperr.Response "Two-factor authentication is already enabled"
+// @Security BearerAuth
+// @Router /protected/enroll-2fa [post]
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	userID, err := GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	usr, err := h.users.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get user").WithCause(err))
+		return
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate secret").WithCause(err))
+		return
+	}
+
+	err = h.users.UpsertTOTPDevice(c.Request.Context(), userID, secret)
+	if errors.Is(err, ErrTOTPAlreadyConfirmed) {
+		apperr.Respond(c, apperr.Conflict("Two-factor authentication is already enabled"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to start enrolment").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(h.cfg.TOTPIssuer, secret, usr.Username),
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary Подтверждение двухфакторной аутентификации первым кодом
+// @Description Возвращает коды восстановления, они показываются один раз.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body totpCodeInput true "Code from the authenticator app"
+// @Success 200 {object} confirmTOTPResponse
+// @Failure 422 {object} apperr.Response "Invalid code"
+// @Failure 429 {object} apperr.Response "Too many failed attempts"
+// @Security BearerAuth
+// @Router /protected/confirm-2fa [post]
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	var input totpCodeInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	userID, err := GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	usr, err := h.users.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get user").WithCause(err))
+		return
+	}
+
+	// Codes are guessed as easily here as at sign-in, e.g. with a stolen
+	// access token, so failures count towards the same throttle.
+	if !h.throttle.allowed(c, "totp", usr.Username) {
+		return
+	}
+
+	device, err := h.users.GetTOTPDevice(c.Request.Context(), userID)
+	if errors.Is(err, ErrTOTPNotEnrolled) {
+		apperr.Respond(c, apperr.Conflict("Two-factor enrolment was not started"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get device").WithCause(err))
+		return
+	}
+
+	step, ok := verifyTOTP(device.Secret, input.Code, time.Now())
+	if !ok {
+		h.throttle.recordFailure(c, "totp", usr.Username)
+		apperr.Respond(c, apperr.InvalidField("code", "invalid", "Invalid code"))
+		return
+	}
+
+	codes, codeHashes, err := newRecoveryCodes()
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate recovery codes").WithCause(err))
+		return
+	}
+
+	err = h.users.ConfirmTOTPDevice(c.Request.Context(), userID, step, codeHashes)
+	if errors.Is(err, ErrTOTPAlreadyConfirmed) {
+		apperr.Respond(c, apperr.Conflict("Two-factor authentication is already enabled"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to enable two-factor authentication").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, confirmTOTPResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableTOTP godoc
+// @Summary Отключение двухфакторной аутентификации
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body totpCodeInput true "Current code or a recovery code"
+// @Failure 422 {object} apperr.Response "Invalid code"
+// @Failure 429 {object} apperr.Response "Too many failed attempts"
+// @Security BearerAuth
+// @Router /protected/disable-2fa [post]
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	var input totpCodeInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	userID, err := GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	usr, err := h.users.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get user").WithCause(err))
+		return
+	}
+
+	// Throttled like ConfirmTOTP.
+	if !h.throttle.allowed(c, "totp", usr.Username) {
+		return
+	}
+
+	valid, err := h.checkSecondFactor(c.Request.Context(), userID, input.Code)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to check code").WithCause(err))
+		return
+	}
+	if !valid {
+		h.throttle.recordFailure(c, "totp", usr.Username)
+		apperr.Respond(c, apperr.InvalidField("code", "invalid", "Invalid code"))
+		return
+	}
+
+	err = h.users.DeleteTOTPDevice(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to disable two-factor authentication").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// checkSecondFactor accepts a fresh TOTP code or an unused recovery code.
+func (h *Handler) checkSecondFactor(ctx context.Context, userID int64, code string) (bool, error) {
+	device, err := h.users.GetTOTPDevice(ctx, userID)
+	if errors.Is(err, ErrTOTPNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if device.ConfirmedAt == nil {
+		return false, nil
+	}
+
+	if step, ok := verifyTOTP(device.Secret, code, time.Now()); ok {
+		return h.users.UseTOTPStep(ctx, userID, step)
+	}
+
+	return h.users.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"3d-backend/internal/ratelimit"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyMFAThrottlesWrongCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	users := NewMemoryUserRepository()
+	userID := users.AddUser(User{Username: "alice", IsActive: true}, "alice@example.com")
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpsertTOTPDevice(ctx, userID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.ConfirmTOTPDevice(ctx, userID, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(users, nil, NewSignInThrottle(ratelimit.NewMemoryStore()), nil, Config{})
+	r := gin.New()
+	r.POST("/verify-2fa", h.VerifyMFA)
+
+	// Each round uses a fresh challenge, as an attacker who knows the
+	// password gets from /sign-in. The failure after the free attempts
+	// blocks the user.
+	var challengeHash string
+	for i := range signInUserBackoff.FreeAttempts + 2 {
+		token, hash, err := NewOpaqueToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := users.InsertMFAChallenge(ctx, userID, hash, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		challengeHash = hash
+
+		w := httptest.NewRecorder()
+		body := `{"challenge_token": "` + token + `", "code": "not-a-code"}`
+		req := httptest.NewRequest(http.MethodPost, "/verify-2fa", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		want := http.StatusUnauthorized
+		if i > signInUserBackoff.FreeAttempts {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("attempt %d: status = %d, want %d", i+1, w.Code, want)
+		}
+	}
+
+	// The throttled attempt did not use up the challenge.
+	if _, err := users.GetMFAChallengeUser(ctx, challengeHash); err != nil {
+		t.Errorf("challenge after throttled attempt: %v", err)
+	}
+}
+
+func TestTOTPSettingsThrottleWrongCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	for _, path := range []string{"/protected/confirm-2fa", "/protected/disable-2fa"} {
+		t.Run(path, func(t *testing.T) {
+			users := NewMemoryUserRepository()
+			userID := users.AddUser(User{Username: "alice", IsActive: true}, "alice@example.com")
+			secret, err := newTOTPSecret()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := users.UpsertTOTPDevice(ctx, userID, secret); err != nil {
+				t.Fatal(err)
+			}
+			if path == "/protected/disable-2fa" {
+				if err := users.ConfirmTOTPDevice(ctx, userID, 0, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			h := NewHandler(users, nil, NewSignInThrottle(ratelimit.NewMemoryStore()), nil, Config{})
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("user_id", strconv.FormatInt(userID, 10)) })
+			r.POST("/protected/confirm-2fa", h.ConfirmTOTP)
+			r.POST("/protected/disable-2fa", h.DisableTOTP)
+
+			// A stolen access token must not buy unlimited guesses at the
+			// code that turns two-factor authentication off.
+			for i := range signInUserBackoff.FreeAttempts + 2 {
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"code": "not-a-code"}`))
+				req.Header.Set("Content-Type", "application/json")
+				r.ServeHTTP(w, req)
+
+				want := http.StatusUnprocessableEntity
+				if i > signInUserBackoff.FreeAttempts {
+					want = http.StatusTooManyRequests
+				}
+				if w.Code != want {
+					t.Fatalf("attempt %d: status = %d, want %d", i+1, w.Code, want)
+				}
+			}
+		})
+	}
+}
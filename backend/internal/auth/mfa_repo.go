@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// maxMFAAttempts is how many codes can be tried against one challenge.
+const maxMFAAttempts = 5
+
+var (
+	ErrTOTPNotEnrolled      = errors.New("totp is not enrolled")
+	ErrInvalidMFAChallenge  = errors.New("invalid mfa challenge")
+	ErrTOTPAlreadyConfirmed = errors.New("totp is already confirmed")
+)
+
+type TOTPDevice struct {
+	UserID       int64      `db:"user_id"`
+	Secret       string     `db:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+}
+
+func (r *PostgresUserRepository) GetTOTPDevice(ctx context.Context, userID int64) (TOTPDevice, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var device TOTPDevice
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step
+		FROM accounts_totpdevice
+		WHERE user_id = $1;
+	`
+	err := r.db.GetContext(ctx, &device, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return device, ErrTOTPNotEnrolled
+		}
+		return device, err
+	}
+	return device, nil
+}
+
+// HasConfirmedTOTP reports whether sign-in needs a second factor.
+func (r *PostgresUserRepository) HasConfirmedTOTP(ctx context.Context, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var confirmed bool
+	query := `SELECT EXISTS (SELECT 1 FROM accounts_totpdevice WHERE user_id = $1 AND confirmed_at IS NOT NULL)`
+	err := r.db.GetContext(ctx, &confirmed, query, userID)
+	if err != nil {
+		return false, err
+	}
+	return confirmed, nil
+}
+
+// UpsertTOTPDevice starts enrolment with a new secret. A confirmed device is
+// never replaced, it has to be disabled first.
+func (r *PostgresUserRepository) UpsertTOTPDevice(ctx context.Context, userID int64, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_totpdevice (user_id, secret, last_used_step, created_at)
+		VALUES ($1, $2, 0, now())
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+		WHERE accounts_totpdevice.confirmed_at IS NULL;
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to store totp device: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTOTPAlreadyConfirmed
+	}
+	return nil
+}
+
+// ConfirmTOTPDevice activates the device and replaces the recovery codes.
+func (r *PostgresUserRepository) ConfirmTOTPDevice(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE accounts_totpdevice
+		SET confirmed_at = now(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL;
+	`
+	res, err := tx.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to confirm totp device: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTOTPAlreadyConfirmed
+	}
+
+	err = insertRecoveryCodes(ctx, tx, userID, recoveryCodeHashes)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func insertRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID int64, codeHashes []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM accounts_recoverycode WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	query := `
+		INSERT INTO accounts_recoverycode (user_id, code_hash, created_at)
+		VALUES ($1, $2, now());
+	`
+	for _, codeHash := range codeHashes {
+		_, err = tx.ExecContext(ctx, query, userID, codeHash)
+		if err != nil {
+			return fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseTOTPStep records a successful code. It fails when the step, or a later
+// one, was already used, which makes every code single-use.
+func (r *PostgresUserRepository) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE accounts_totpdevice
+		SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2;
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *PostgresUserRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE accounts_recoverycode
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *PostgresUserRepository) DeleteTOTPDevice(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM accounts_recoverycode WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM accounts_totpdevice WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete totp device: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) InsertMFAChallenge(ctx context.Context, userID int64, tokenHash string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_mfachallenge (user_id, token_hash, attempts, created_at, expires_at)
+		VALUES ($1, $2, 0, now(), now() + $3 * interval '1 second');
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, tokenHash, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// GetMFAChallengeUser returns the user of a live challenge without counting
+// an attempt.
+func (r *PostgresUserRepository) GetMFAChallengeUser(ctx context.Context, tokenHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT user_id FROM accounts_mfachallenge
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2;
+	`
+	var userID int64
+	err := r.db.GetContext(ctx, &userID, query, tokenHash, maxMFAAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// AttemptMFAChallenge counts an attempt against a live challenge and returns
+// its user. Challenges stop working after maxMFAAttempts codes.
+func (r *PostgresUserRepository) AttemptMFAChallenge(ctx context.Context, tokenHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE accounts_mfachallenge
+		SET attempts = attempts + 1
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+		RETURNING user_id;
+	`
+	var userID int64
+	err := r.db.GetContext(ctx, &userID, query, tokenHash, maxMFAAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (r *PostgresUserRepository) MarkMFAChallengeUsed(ctx context.Context, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE accounts_mfachallenge SET used_at = now() WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to use mfa challenge: %w", err)
+	}
+	return nil
+}
@@ -1,51 +1,166 @@
 package auth
 
 import (
+	"3d-backend/internal/apperr"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts a Bearer access token or an API key, passed either
+// as a Bearer token or in the X-API-Key header.
+func AuthMiddleware(users UserRepository, keys *KeySet) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, users, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			apperr.Respond(c, apperr.Unauthenticated("Authorization header is required"))
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
-			c.Abort()
+			apperr.Respond(c, apperr.Unauthenticated("Bearer token required"))
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(os.Getenv("JWT_TOKEN")), nil
-		})
+		if strings.HasPrefix(tokenString, apiKeyPrefix) {
+			authenticateAPIKey(c, users, tokenString)
+			return
+		}
+
+		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			apperr.Respond(c, apperr.Unauthenticated("Invalid token"))
+			return
+		}
+
+		claims, ok := token.Claims.(*Claims)
+		if !ok || claims.ID == "" || claims.SessionID == 0 {
+			apperr.Respond(c, apperr.Unauthenticated("Invalid token claims"))
+			return
+		}
+
+		userID, err := strconv.ParseInt(claims.ID, 10, 64)
+		if err != nil {
+			apperr.Respond(c, apperr.Unauthenticated("Invalid token claims"))
+			return
+		}
+
+		session, err := users.GetSessionState(c.Request.Context(), claims.SessionID, userID)
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to check session").WithCause(err))
+			return
+		}
+		if !session.Active {
+			apperr.Respond(c, apperr.Unauthenticated("Session revoked"))
+			return
+		}
+
+		c.Set("user_id", claims.ID) // `jti` carries the user ID
+		c.Set("session_id", claims.SessionID)
+		c.Set("mfa_verified", session.MFAVerified)
+
+		c.Next()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, users UserRepository, apiKey string) {
+	key, err := users.AuthenticateAPIKey(c.Request.Context(), HashToken(apiKey))
+	if errors.Is(err, ErrInvalidAPIKey) {
+		apperr.Respond(c, apperr.Unauthenticated("Invalid api key"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to check api key").WithCause(err))
+		return
+	}
+
+	if key.ReadOnly && !isSafeMethod(c.Request.Method) {
+		apperr.Respond(c, apperr.Forbidden("API key is read-only"))
+		return
+	}
+
+	c.Set("user_id", strconv.FormatInt(key.UserID, 10))
+	c.Set("api_key_id", key.ID)
+	c.Set("mfa_verified", key.MFAVerified)
+	if key.ProjectID != nil {
+		c.Set("api_key_project_id", *key.ProjectID)
+	}
+
+	c.Next()
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// DenyProjectScopedAPIKeys guards routes that are not about a single project
+// from API keys limited to one.
+func DenyProjectScopedAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, scoped := GetAPIKeyProjectID(c); scoped {
+			apperr.Respond(c, apperr.Forbidden("API key is limited to a single project"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func AdminMiddleware(users UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if ok && token.Valid {
-			c.Set("user_id", claims["jti"]) // Assuming `jti` is the user ID in claims
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		isStaff, err := users.IsStaffUser(c.Request.Context(), userID)
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to get user").WithCause(err))
+			return
+		}
+		if !isStaff {
+			apperr.Respond(c, apperr.Forbidden("Admin privileges required"))
 			return
 		}
 
 		c.Next()
 	}
 }
+
+// RequirePermission rejects the request unless the current user holds every
+// listed Django permission, as granted in the admin.
+func RequirePermission(users UserRepository, perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+			return
+		}
+
+		for _, perm := range perms {
+			allowed, err := users.HasPermission(c.Request.Context(), userID, perm)
+			if err != nil {
+				apperr.Respond(c, apperr.Internal("Failed to check permissions").WithCause(err))
+				return
+			}
+			if !allowed {
+				apperr.Respond(c, apperr.Forbidden(fmt.Sprintf("Permission %s required", perm)))
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrOIDCIdentityNotFound = errors.New("oidc identity not found")
+	ErrInvalidOIDCState     = errors.New("invalid oidc state")
+)
+
+type OIDCLoginState struct {
+	CodeVerifier string    `db:"code_verifier"`
+	Nonce        string    `db:"nonce"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+func (r *PostgresUserRepository) InsertOIDCLoginState(ctx context.Context, stateHash string, codeVerifier string, nonce string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_oidcloginstate (state_hash, code_verifier, nonce, created_at, expires_at)
+		VALUES ($1, $2, $3, now(), now() + $4 * interval '1 second');
+	`
+	_, err := r.db.ExecContext(ctx, query, stateHash, codeVerifier, nonce, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to store oidc state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeOIDCLoginState deletes the state so every login attempt can be
+// completed only once.
+func (r *PostgresUserRepository) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (OIDCLoginState, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var state OIDCLoginState
+	query := `
+		DELETE FROM accounts_oidcloginstate
+		WHERE state_hash = $1
+		RETURNING code_verifier, nonce, expires_at;
+	`
+	err := r.db.GetContext(ctx, &state, query, stateHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return state, ErrInvalidOIDCState
+		}
+		return state, err
+	}
+	if state.ExpiresAt.Before(time.Now()) {
+		return state, ErrInvalidOIDCState
+	}
+	return state, nil
+}
+
+func (r *PostgresUserRepository) GetUserByOIDCIdentity(ctx context.Context, issuer string, subject string) (User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var usr User
+	query := `
+		SELECT u.id, u.username, u.password, u.is_active, u.is_staff, u.is_superuser
+		FROM auth_user u
+		JOIN accounts_oidcidentity oi ON oi.user_id = u.id
+		WHERE oi.issuer = $1 AND oi.subject = $2;
+	`
+	err := r.db.GetContext(ctx, &usr, query, issuer, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return usr, ErrOIDCIdentityNotFound
+		}
+		return usr, err
+	}
+	return usr, nil
+}
+
+func (r *PostgresUserRepository) InsertOIDCIdentity(ctx context.Context, issuer string, subject string, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_oidcidentity (issuer, subject, user_id, created_at)
+		VALUES ($1, $2, $3, now());
+	`
+	_, err := r.db.ExecContext(ctx, query, issuer, subject, userID)
+	if err != nil {
+		return fmt.Errorf("failed to link oidc identity: %w", err)
+	}
+	return nil
+}
+
+// InsertOIDCUser provisions a user for an identity seen for the first time.
+// The password is unusable, so the account can only sign in through the
+// provider until a password is set.
+func (r *PostgresUserRepository) InsertOIDCUser(ctx context.Context, issuer string, subject string, username string, email string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	password, err := unusablePassword()
+	if err != nil {
+		return 0, err
+	}
+
+	userID, err := insertUser(ctx, tx, username, email, password)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO accounts_oidcidentity (issuer, subject, user_id, created_at)
+		VALUES ($1, $2, $3, now());
+	`
+	_, err = tx.ExecContext(ctx, query, issuer, subject, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to link oidc identity: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,540 @@
+package auth
+
+import (
+	"3d-backend/internal/ratelimit"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "backend"
+	testClientSecret = "client secret"
+	testRedirectURL  = "https://app.example.com/oidc/callback"
+)
+
+// mockIdP is an in-process OpenID provider. It signs in whoever is set as
+// login, checks PKCE on the token endpoint and issues RS256 ID tokens.
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu sync.Mutex
+	// login is the identity the next authorization signs in.
+	login jwt.MapClaims
+	// editDiscovery and editIDToken let tests break the provider's answers.
+	editDiscovery func(doc map[string]any)
+	editIDToken   func(claims jwt.MapClaims)
+	codes         map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	query url.Values
+	login jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{t: t, key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("GET /authorize", idp.authorize)
+	mux.HandleFunc("POST /token", idp.token)
+	mux.HandleFunc("GET /jwks", idp.jwks)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) issuer() string {
+	return idp.server.URL
+}
+
+func (idp *mockIdP) provider() *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Issuer:       idp.issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	}, idp.server.Client())
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	doc := map[string]any{
+		"issuer":                                idp.issuer(),
+		"authorization_endpoint":                idp.issuer() + "/authorize",
+		"token_endpoint":                        idp.issuer() + "/token",
+		"jwks_uri":                              idp.issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"code_challenge_methods_supported":      []string{"plain", "S256"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	}
+	idp.mu.Lock()
+	if idp.editDiscovery != nil {
+		idp.editDiscovery(doc)
+	}
+	idp.mu.Unlock()
+	json.NewEncoder(w).Encode(doc)
+}
+
+// authorize signs the current login in and redirects back with a code, as
+// the provider does after the user authenticated.
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != testClientID || query.Get("redirect_uri") != testRedirectURL {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	code, _, err := NewOpaqueToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	idp.mu.Lock()
+	idp.codes[code] = mockAuthorization{query: query, login: idp.login}
+	idp.mu.Unlock()
+
+	redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != url.QueryEscape(testClientSecret) {
+		http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	idp.mu.Lock()
+	auth, ok := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	editIDToken := idp.editIDToken
+	idp.mu.Unlock()
+
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != auth.query.Get("redirect_uri") {
+		http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	if pkceChallenge(r.PostFormValue("code_verifier")) != auth.query.Get("code_challenge") {
+		http.Error(w, `{"error": "invalid_grant", "error_description": "PKCE verification failed"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   idp.issuer(),
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"nonce": auth.query.Get("nonce"),
+	}
+	for name, value := range auth.login {
+		claims[name] = value
+	}
+	if editIDToken != nil {
+		editIDToken(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "idp-key"
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(oidcTokenResponse{IDToken: idToken, AccessToken: "access", TokenType: "Bearer"})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(JWKS{Keys: []JWK{{
+		KTY: "RSA",
+		KID: "idp-key",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+	}}})
+}
+
+// signIn runs the browser's part of the flow: it follows the authorization
+// URL and returns the code and state the provider redirected back with.
+func (idp *mockIdP) signIn(authorizationURL string, login jwt.MapClaims) (code string, state string) {
+	idp.t.Helper()
+
+	idp.mu.Lock()
+	idp.login = login
+	idp.mu.Unlock()
+
+	// A copy, the provider shares the server's client.
+	client := *idp.server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authorizationURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		idp.t.Fatalf("authorize: status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func testKeySet(t *testing.T) *KeySet {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "test.pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(doc map[string]any)
+		wantErr string
+	}{
+		{"valid", nil, ""},
+		{"other issuer", func(doc map[string]any) { doc["issuer"] = "https://evil.example.com" }, "does not match configured issuer"},
+		{"no token endpoint", func(doc map[string]any) { delete(doc, "token_endpoint") }, "lacks authorization, token or jwks endpoint"},
+		{"implicit flow only", func(doc map[string]any) { doc["response_types_supported"] = []string{"id_token"} }, "authorization code flow"},
+		{"plain PKCE only", func(doc map[string]any) { doc["code_challenge_methods_supported"] = []string{"plain"} }, "PKCE with S256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.editDiscovery = tt.edit
+
+			authURL, err := idp.provider().AuthorizationURL(context.Background(), "state", "nonce", "verifier")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			u, err := url.Parse(authURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.Scheme + "://" + u.Host + u.Path; got != idp.issuer()+"/authorize" {
+				t.Errorf("endpoint = %q, want the discovered one", got)
+			}
+			want := url.Values{
+				"response_type":         {"code"},
+				"client_id":             {testClientID},
+				"redirect_uri":          {testRedirectURL},
+				"scope":                 {"openid email profile"},
+				"state":                 {"state"},
+				"nonce":                 {"nonce"},
+				"code_challenge":        {pkceChallenge("verifier")},
+				"code_challenge_method": {"S256"},
+			}
+			if got := u.Query(); got.Encode() != want.Encode() {
+				t.Errorf("query = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	login := jwt.MapClaims{"sub": "alice-sub", "email": "alice@example.com", "email_verified": true}
+
+	tests := []struct {
+		name         string
+		edit         func(claims jwt.MapClaims)
+		codeVerifier string
+		wantErr      string
+	}{
+		{"valid", nil, "verifier", ""},
+		{"wrong code verifier", nil, "other verifier", "PKCE verification failed"},
+		{"other nonce", func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }, "verifier", "nonce mismatch"},
+		{"other audience", func(claims jwt.MapClaims) { claims["aud"] = "other-client" }, "verifier", "audience"},
+		{"other issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }, "verifier", "issuer"},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }, "verifier", "expired"},
+		{"no subject", func(claims jwt.MapClaims) { delete(claims, "sub") }, "verifier", "no subject"},
+		{"azp of another client", func(claims jwt.MapClaims) {
+			claims["aud"] = []string{testClientID, "other-client"}
+			claims["azp"] = "other-client"
+		}, "verifier", "azp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.editIDToken = tt.edit
+			provider := idp.provider()
+
+			authURL, err := provider.AuthorizationURL(context.Background(), "state", "nonce", "verifier")
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, _ := idp.signIn(authURL, login)
+
+			claims, err := provider.Exchange(context.Background(), code, tt.codeVerifier, "nonce")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != "alice-sub" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+// oidcTestServer serves the OIDC endpoints of the API against idp.
+func oidcTestServer(t *testing.T, idp *mockIdP, users *MemoryUserRepository) (*gin.Engine, *KeySet) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	ks := testKeySet(t)
+	h := NewHandler(users, ks, NewSignInThrottle(ratelimit.NewMemoryStore()), nil, Config{
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+		OIDCStateTTL:    time.Minute,
+		OIDCProvider:    idp.provider(),
+	})
+	r := gin.New()
+	r.GET("/oidc/login", h.OIDCLogin)
+	r.POST("/oidc/callback", h.OIDCCallback)
+	return r, ks
+}
+
+// oidcSignIn runs the whole flow for login and returns the callback response.
+func oidcSignIn(t *testing.T, r *gin.Engine, idp *mockIdP, login jwt.MapClaims) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status = %d, body %s", w.Code, w.Body)
+	}
+	var started oidcLoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil {
+		t.Fatal(err)
+	}
+
+	code, state := idp.signIn(started.AuthorizationURL, login)
+	return oidcCallback(r, code, state)
+}
+
+func oidcCallback(r *gin.Engine, code, state string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(oidcCallbackInput{Code: code, State: state})
+	req := httptest.NewRequest(http.MethodPost, "/oidc/callback", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// signedInUser returns the user ID of the access token in a sign-in response.
+func signedInUser(t *testing.T, ks *KeySet, w *httptest.ResponseRecorder) int64 {
+	t.Helper()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: status = %d, body %s", w.Code, w.Body)
+	}
+	var response signInResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	claims := &Claims{}
+	if _, err := jwt.ParseWithClaims(response.Token, claims, ks.Keyfunc); err != nil {
+		t.Fatal(err)
+	}
+	userID, err := strconv.ParseInt(claims.ID, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userID
+}
+
+func TestOIDCCallbackLinksVerifiedEmail(t *testing.T) {
+	idp := newMockIdP(t)
+	users := NewMemoryUserRepository()
+	aliceID := users.AddUser(User{Username: "alice", IsActive: true}, "Alice@example.com")
+	r, ks := oidcTestServer(t, idp, users)
+
+	login := jwt.MapClaims{"sub": "alice-sub", "email": "alice@example.com", "email_verified": true}
+	if got := signedInUser(t, ks, oidcSignIn(t, r, idp, login)); got != aliceID {
+		t.Fatalf("signed in user %d, want existing user %d", got, aliceID)
+	}
+
+	// The identity is linked now, a changed email does not matter.
+	login = jwt.MapClaims{"sub": "alice-sub", "email": "alice@new.example.com", "email_verified": true}
+	if got := signedInUser(t, ks, oidcSignIn(t, r, idp, login)); got != aliceID {
+		t.Fatalf("second sign-in as user %d, want %d", got, aliceID)
+	}
+}
+
+func TestOIDCCallbackProvisionsUsers(t *testing.T) {
+	tests := []struct {
+		name         string
+		login        jwt.MapClaims
+		wantUsername string // ending in - for a suffixed name
+		wantEmail    string
+	}{
+		{"new user", jwt.MapClaims{"sub": "bob-sub", "preferred_username": "bob", "email": "bob@example.com", "email_verified": true}, "bob", "bob@example.com"},
+		// An unverified address could belong to anybody, so it neither
+		// links nor is stored.
+		{"unverified email", jwt.MapClaims{"sub": "eve-sub", "email": "alice@example.com", "email_verified": false}, "alice-", ""},
+		{"taken username", jwt.MapClaims{"sub": "other-alice", "preferred_username": "alice"}, "alice-", ""},
+		{"no usable claims", jwt.MapClaims{"sub": "1234"}, "oidc-1234", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			users := NewMemoryUserRepository()
+			aliceID := users.AddUser(User{Username: "alice", IsActive: true}, "alice@example.com")
+			r, ks := oidcTestServer(t, idp, users)
+
+			userID := signedInUser(t, ks, oidcSignIn(t, r, idp, tt.login))
+			if userID == aliceID {
+				t.Fatal("signed in as the existing user")
+			}
+
+			usr, err := users.GetUserByID(context.Background(), userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// A taken username gets a random suffix.
+			if got := usr.Username; got != tt.wantUsername && !(strings.HasSuffix(tt.wantUsername, "-") && strings.HasPrefix(got, tt.wantUsername)) {
+				t.Errorf("username = %q, want %q", usr.Username, tt.wantUsername)
+			}
+			linked, err := users.GetUserByOIDCIdentity(context.Background(), idp.issuer(), tt.login["sub"].(string))
+			if err != nil || linked.ID != userID {
+				t.Errorf("identity linked to %d (%v), want %d", linked.ID, err, userID)
+			}
+			withEmail, err := users.GetActiveUsersByEmail(context.Background(), "alice@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(withEmail) != 1 {
+				t.Errorf("%d users with alice's email, want 1", len(withEmail))
+			}
+			if tt.wantEmail != "" {
+				found, err := users.GetActiveUsersByEmail(context.Background(), tt.wantEmail)
+				if err != nil || len(found) != 1 || found[0].ID != userID {
+					t.Errorf("email %s not stored for the new user", tt.wantEmail)
+				}
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	login := jwt.MapClaims{"sub": "alice-sub", "email": "alice@example.com", "email_verified": true}
+
+	tests := []struct {
+		name string
+		edit func(claims jwt.MapClaims)
+	}{
+		{"other nonce", func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }},
+		{"other audience", func(claims jwt.MapClaims) { claims["aud"] = "other-client" }},
+		{"other issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.editIDToken = tt.edit
+			users := NewMemoryUserRepository()
+			aliceID := users.AddUser(User{Username: "alice", IsActive: true}, "alice@example.com")
+			r, _ := oidcTestServer(t, idp, users)
+
+			w := oidcSignIn(t, r, idp, login)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+			if _, err := users.GetUserByOIDCIdentity(context.Background(), idp.issuer(), "alice-sub"); err != ErrOIDCIdentityNotFound {
+				t.Errorf("identity linked after a rejected login: %v", err)
+			}
+			if _, err := users.GetUserByID(context.Background(), aliceID+1); err == nil {
+				t.Error("user provisioned after a rejected login")
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	idp := newMockIdP(t)
+	users := NewMemoryUserRepository()
+	r, ks := oidcTestServer(t, idp, users)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+	var started oidcLoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.signIn(started.AuthorizationURL, jwt.MapClaims{"sub": "bob-sub", "preferred_username": "bob"})
+
+	if w := oidcCallback(r, code, "forged state"); w.Code != http.StatusBadRequest {
+		t.Errorf("forged state: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	signedInUser(t, ks, oidcCallback(r, code, state))
+	if w := oidcCallback(r, code, state); w.Code != http.StatusBadRequest {
+		t.Errorf("replayed state: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
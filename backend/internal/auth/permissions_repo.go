@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func (r *PostgresUserRepository) GetUserGroups(ctx context.Context, userID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	groups := []string{}
+	query := `
+		SELECT g.name
+		FROM auth_group g
+		JOIN auth_user_groups ug ON ug.group_id = g.id
+		WHERE ug.user_id = $1
+		ORDER BY g.name;
+	`
+	err := r.db.SelectContext(ctx, &groups, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// HasPermission follows Django's ModelBackend: inactive users have no
+// permissions, superusers have all of them, everyone else gets the union of
+// their own and their groups' permissions. perm is "app_label.codename", e.g.
+// "projects.change_building".
+func (r *PostgresUserRepository) HasPermission(ctx context.Context, userID int64, perm string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	appLabel, codename, found := strings.Cut(perm, ".")
+	if !found {
+		return false, fmt.Errorf("invalid permission: %s", perm)
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM auth_user u
+			WHERE u.id = $1 AND u.is_active AND (u.is_superuser OR EXISTS (
+				SELECT 1
+				FROM auth_permission p
+				JOIN django_content_type ct ON ct.id = p.content_type_id
+				WHERE ct.app_label = $2 AND p.codename = $3 AND (
+					EXISTS (
+						SELECT 1 FROM auth_user_user_permissions up
+						WHERE up.user_id = u.id AND up.permission_id = p.id
+					) OR EXISTS (
+						SELECT 1 FROM auth_user_groups ug
+						JOIN auth_group_permissions gp ON gp.group_id = ug.group_id
+						WHERE ug.user_id = u.id AND gp.permission_id = p.id
+					)
+				)
+			))
+		);
+	`
+	var allowed bool
+	err := r.db.GetContext(ctx, &allowed, query, userID, appLabel, codename)
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
@@ -1,26 +1,91 @@
 package auth
 
 import (
+	"3d-backend/internal/tracing"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type User struct {
-	ID       int64  `db:"id"`
-	Username string `db:"username"`
-	Password string `db:"password"`
+	ID          int64    `db:"id"`
+	Username    string   `db:"username"`
+	Password    string   `db:"password"`
+	IsActive    bool     `db:"is_active"`
+	IsStaff     bool     `db:"is_staff"`
+	IsSuperuser bool     `db:"is_superuser"`
+	Groups      []string `db:"-"`
+}
+
+const userColumns = `id, username, password, is_active, is_staff, is_superuser`
+
+func (r *PostgresUserRepository) GetUserHashByUsername(ctx context.Context, username string) (usr User, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "auth.GetUserHashByUsername")
+	defer func() { tracing.EndQuery(span, 1, err) }()
+
+	err = r.db.GetContext(ctx, &usr, `SELECT `+userColumns+` FROM auth_user WHERE username = $1`, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return usr, ErrUserNotFound
+		}
+		return usr, err
+	}
+	return usr, nil
+}
+
+func (r *PostgresUserRepository) IsStaffUser(ctx context.Context, userID int64) (isStaff bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "auth.IsStaffUser", attribute.Int64("user.id", userID))
+	defer func() { tracing.EndQuery(span, 1, err) }()
+
+	err = r.db.GetContext(ctx, &isStaff, `SELECT is_active AND (is_staff OR is_superuser) FROM auth_user WHERE id = $1`, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+		return false, err
+	}
+	return isStaff, nil
 }
 
-func GetUserHashByUsername(db *sqlx.DB, username string) (User, error) {
-	var usr User
-	err := db.Get(&usr, `SELECT id, password FROM auth_user WHERE username = $1`, username)
+func (r *PostgresUserRepository) GetUserByID(ctx context.Context, userID int64) (usr User, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "auth.GetUserByID", attribute.Int64("user.id", userID))
+	defer func() { tracing.EndQuery(span, 1, err) }()
+
+	err = r.db.GetContext(ctx, &usr, `SELECT `+userColumns+` FROM auth_user WHERE id = $1`, userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return usr, fmt.Errorf("user not found")
+			return usr, ErrUserNotFound
 		}
 		return usr, err
 	}
 	return usr, nil
 }
+
+func (r *PostgresUserRepository) UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "auth.UpdateUserPassword", attribute.Int64("user.id", userID))
+	var rows int64
+	defer func() { tracing.EndQuery(span, rows, err) }()
+
+	res, err := r.db.ExecContext(ctx, `UPDATE auth_user SET password = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	rows, _ = res.RowsAffected()
+	return nil
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+type Session struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"-"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	IPAddress  string     `db:"ip_address" json:"ip_address"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt time.Time  `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"-"`
+	Current    bool       `db:"-" json:"current"`
+}
+
+type refreshTokenRow struct {
+	ID               int64      `db:"id"`
+	SessionID        int64      `db:"session_id"`
+	UserID           int64      `db:"user_id"`
+	ExpiresAt        time.Time  `db:"expires_at"`
+	UsedAt           *time.Time `db:"used_at"`
+	SessionRevokedAt *time.Time `db:"session_revoked_at"`
+	SessionExpiresAt time.Time  `db:"session_expires_at"`
+}
+
+// InsertSession opens a session for the user and stores its first refresh
+// token. mfaVerified records whether the sign-in passed a second factor.
+func (r *PostgresUserRepository) InsertSession(ctx context.Context, userID int64, userAgent string, ipAddress string, refreshTokenHash string, mfaVerified bool, ttl time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO accounts_session (user_id, user_agent, ip_address, mfa_verified, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, now(), now(), now() + $5 * interval '1 second')
+		RETURNING id;
+	`
+	var sessionID int64
+	err = tx.GetContext(ctx, &sessionID, query, userID, userAgent, ipAddress, mfaVerified, ttl.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	err = insertRefreshToken(ctx, tx, sessionID, refreshTokenHash, ttl)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return sessionID, nil
+}
+
+func insertRefreshToken(ctx context.Context, tx *sqlx.Tx, sessionID int64, tokenHash string, ttl time.Duration) error {
+	query := `
+		INSERT INTO accounts_refreshtoken (session_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, now(), now() + $3 * interval '1 second');
+	`
+	_, err := tx.ExecContext(ctx, query, sessionID, tokenHash, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// RotateRefreshToken exchanges a valid refresh token for a new one. Presenting
+// a token that was already rotated means it leaked, so the whole session is
+// revoked and ErrRefreshTokenReused is returned.
+func (r *PostgresUserRepository) RotateRefreshToken(ctx context.Context, tokenHash string, newTokenHash string, ttl time.Duration) (int64, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		SELECT rt.id, rt.session_id, s.user_id, rt.expires_at, rt.used_at,
+			s.revoked_at AS session_revoked_at, s.expires_at AS session_expires_at
+		FROM accounts_refreshtoken rt
+		JOIN accounts_session s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE;
+	`
+	var row refreshTokenRow
+	err = tx.GetContext(ctx, &row, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, ErrInvalidRefreshToken
+		}
+		return 0, 0, err
+	}
+
+	if row.UsedAt != nil {
+		_, err = tx.ExecContext(ctx, `UPDATE accounts_session SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, row.SessionID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err = tx.Commit(); err != nil {
+			return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return 0, 0, ErrRefreshTokenReused
+	}
+
+	now := time.Now()
+	if row.SessionRevokedAt != nil || row.ExpiresAt.Before(now) || row.SessionExpiresAt.Before(now) {
+		return 0, 0, ErrInvalidRefreshToken
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE accounts_refreshtoken SET used_at = now() WHERE id = $1`, row.ID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+
+	err = insertRefreshToken(ctx, tx, row.SessionID, newTokenHash, ttl)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE accounts_session SET last_used_at = now() WHERE id = $1`, row.SessionID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return row.UserID, row.SessionID, nil
+}
+
+type SessionState struct {
+	Active      bool `db:"active"`
+	MFAVerified bool `db:"mfa_verified"`
+}
+
+// GetSessionState reports a session as inactive also for users deactivated in
+// the admin, so their access tokens stop working without waiting for expiry.
+func (r *PostgresUserRepository) GetSessionState(ctx context.Context, sessionID int64, userID int64) (SessionState, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var state SessionState
+	query := `
+		SELECT s.revoked_at IS NULL AND s.expires_at > now() AND u.is_active AS active, s.mfa_verified
+		FROM accounts_session s
+		JOIN auth_user u ON u.id = s.user_id
+		WHERE s.id = $1 AND s.user_id = $2;
+	`
+	err := r.db.GetContext(ctx, &state, query, sessionID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return state, nil
+		}
+		return state, err
+	}
+	return state, nil
+}
+
+func (r *PostgresUserRepository) GetActiveSessions(ctx context.Context, userID int64) ([]Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	sessions := []Session{}
+	query := `
+		SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at
+		FROM accounts_session
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_used_at DESC;
+	`
+	err := r.db.SelectContext(ctx, &sessions, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *PostgresUserRepository) MarkSessionRevoked(ctx context.Context, sessionID int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE accounts_session
+		SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+	`
+	res, err := r.db.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except the current one.
+func (r *PostgresUserRepository) RevokeOtherSessions(ctx context.Context, userID int64, currentSessionID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE accounts_session
+		SET revoked_at = now()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL;
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, currentSessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
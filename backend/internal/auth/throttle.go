@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"3d-backend/internal/logging"
+	"3d-backend/internal/metrics"
+	"3d-backend/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// Sign-in failures are throttled per username and, with a higher allowance
+// for users behind a shared NAT, per client IP.
+var (
+	signInUserBackoff = ratelimit.Backoff{
+		Name:         "sign-in-user",
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Minute,
+		LockoutAfter: 10,
+		Lockout:      15 * time.Minute,
+		Window:       time.Hour,
+	}
+	signInIPBackoff = ratelimit.Backoff{
+		Name:         "sign-in-ip",
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Minute,
+		LockoutAfter: 100,
+		Lockout:      time.Hour,
+		Window:       time.Hour,
+	}
+)
+
+// SignInThrottle backs off failed sign-ins per username and per client IP. The
+// counters live in store, shared by all instances when it is a PostgresStore.
+type SignInThrottle struct {
+	store ratelimit.Store
+}
+
+func NewSignInThrottle(store ratelimit.Store) *SignInThrottle {
+	return &SignInThrottle{store: store}
+}
+
+// allowed responds with 429 while the username or client IP is blocked. Store
+// errors are logged and do not block sign-in. method is the sign-in step,
+// password or totp, the attempt is counted under.
+func (t *SignInThrottle) allowed(c *gin.Context, method, username string) bool {
+	var retryAfter time.Duration
+	for _, k := range signInThrottleKeys(c, username) {
+		blocked, err := k.backoff.Check(c.Request.Context(), t.store, k.key)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Failed to check throttle", "throttle", k.backoff.Name, "error", err)
+			continue
+		}
+		retryAfter = max(retryAfter, blocked)
+	}
+
+	if retryAfter > 0 {
+		metrics.SignIns.WithLabelValues(method, "throttled").Inc()
+		ratelimit.TooManyRequests(c, retryAfter)
+		return false
+	}
+	return true
+}
+
+func (t *SignInThrottle) recordFailure(c *gin.Context, method, username string) {
+	metrics.SignIns.WithLabelValues(method, "failure").Inc()
+
+	for _, k := range signInThrottleKeys(c, username) {
+		if _, err := k.backoff.Fail(c.Request.Context(), t.store, k.key); err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Failed to record throttle failure", "throttle", k.backoff.Name, "error", err)
+		}
+	}
+}
+
+// reset forgets the failures of a user once a session starts, not after the
+// password alone: wrong second factors count as failures too.
+func (t *SignInThrottle) reset(c *gin.Context, username string) {
+	if err := signInUserBackoff.Succeed(c.Request.Context(), t.store, strings.ToLower(username)); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("Failed to reset throttle", "throttle", signInUserBackoff.Name, "error", err)
+	}
+}
+
+type signInThrottleKey struct {
+	backoff ratelimit.Backoff
+	key     string
+}
+
+func signInThrottleKeys(c *gin.Context, username string) []signInThrottleKey {
+	return []signInThrottleKey{
+		{backoff: signInUserBackoff, key: strings.ToLower(username)},
+		{backoff: signInIPBackoff, key: c.ClientIP()},
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// UserRepository is the storage behind the auth handlers and middlewares:
+// users and their permissions, sessions, password resets, API keys, second
+// factors and linked OIDC identities. PostgresUserRepository works on the
+// tables of the Django admin, MemoryUserRepository keeps everything in memory
+// for tests.
+type UserRepository interface {
+	GetUserHashByUsername(ctx context.Context, username string) (User, error)
+	GetUserByID(ctx context.Context, userID int64) (User, error)
+	IsStaffUser(ctx context.Context, userID int64) (bool, error)
+	UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error
+	InsertUser(ctx context.Context, username string, email string, passwordHash string) (int64, error)
+	GetActiveUsersByEmail(ctx context.Context, email string) ([]User, error)
+
+	GetUserGroups(ctx context.Context, userID int64) ([]string, error)
+	HasPermission(ctx context.Context, userID int64, perm string) (bool, error)
+
+	InsertPasswordResetToken(ctx context.Context, userID int64, tokenHash string, ttl time.Duration) error
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (int64, error)
+
+	InsertSession(ctx context.Context, userID int64, userAgent string, ipAddress string, refreshTokenHash string, mfaVerified bool, ttl time.Duration) (int64, error)
+	RotateRefreshToken(ctx context.Context, tokenHash string, newTokenHash string, ttl time.Duration) (int64, int64, error)
+	GetSessionState(ctx context.Context, sessionID int64, userID int64) (SessionState, error)
+	GetActiveSessions(ctx context.Context, userID int64) ([]Session, error)
+	MarkSessionRevoked(ctx context.Context, sessionID int64, userID int64) error
+	RevokeOtherSessions(ctx context.Context, userID int64, currentSessionID int64) error
+
+	InsertAPIKey(ctx context.Context, key NewAPIKey) (int64, error)
+	GetAPIKeys(ctx context.Context, userID int64) ([]APIKey, error)
+	AuthenticateAPIKey(ctx context.Context, keyHash string) (APIKey, error)
+	MarkAPIKeyRevoked(ctx context.Context, keyID int64, userID int64) error
+
+	GetTOTPDevice(ctx context.Context, userID int64) (TOTPDevice, error)
+	HasConfirmedTOTP(ctx context.Context, userID int64) (bool, error)
+	UpsertTOTPDevice(ctx context.Context, userID int64, secret string) error
+	ConfirmTOTPDevice(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	DeleteTOTPDevice(ctx context.Context, userID int64) error
+	InsertMFAChallenge(ctx context.Context, userID int64, tokenHash string, ttl time.Duration) error
+	GetMFAChallengeUser(ctx context.Context, tokenHash string) (int64, error)
+	AttemptMFAChallenge(ctx context.Context, tokenHash string) (int64, error)
+	MarkMFAChallengeUsed(ctx context.Context, tokenHash string) error
+
+	InsertOIDCLoginState(ctx context.Context, stateHash string, codeVerifier string, nonce string, ttl time.Duration) error
+	ConsumeOIDCLoginState(ctx context.Context, stateHash string) (OIDCLoginState, error)
+	GetUserByOIDCIdentity(ctx context.Context, issuer string, subject string) (User, error)
+	InsertOIDCIdentity(ctx context.Context, issuer string, subject string, userID int64) error
+	InsertOIDCUser(ctx context.Context, issuer string, subject string, username string, email string) (int64, error)
+}
+
+type PostgresUserRepository struct {
+	db           *sqlx.DB
+	queryTimeout time.Duration
+}
+
+// NewPostgresUserRepository returns a repository whose every method gives up
+// after queryTimeout, or earlier when its context ends.
+func NewPostgresUserRepository(db *sqlx.DB, queryTimeout time.Duration) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db, queryTimeout: queryTimeout}
+}
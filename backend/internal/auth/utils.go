@@ -1,50 +1,115 @@
 package auth
 
 import (
-	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/pbkdf2"
-	"os"
+	"math/big"
 	"strconv"
-	"strings"
 	"time"
 )
 
-func GenerateToken(usr User) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		ID:        strconv.FormatInt(usr.ID, 10),
+const saltChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Claims are the access token claims. The user id stays in jti for
+// compatibility with tokens issued before sessions existed. Staff, superuser
+// and groups are informational for clients; authorization re-reads them from
+// the database.
+type Claims struct {
+	SessionID int64    `json:"sid"`
+	Staff     bool     `json:"staff,omitempty"`
+	Superuser bool     `json:"superuser,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func GenerateToken(keys *KeySet, usr User, sessionID int64, ttl time.Duration) (string, error) {
+	return keys.Sign(Claims{
+		SessionID: sessionID,
+		Staff:     usr.IsStaff,
+		Superuser: usr.IsSuperuser,
+		Groups:    usr.Groups,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			ID:        strconv.FormatInt(usr.ID, 10),
+		},
 	})
-	return token.SignedString([]byte(os.Getenv("JWT_TOKEN")))
 }
 
-func VerifyDjangoPassword(password, djangoHash string) (bool, error) {
-	parts := strings.Split(djangoHash, "$")
-	if len(parts) != 4 {
-		return false, errors.New("invalid hash format")
+// NewOpaqueToken returns a random token and the hash that is stored for it.
+// It backs refresh tokens and password reset tokens.
+func NewOpaqueToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
 	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
 
-	algorithm, iterStr, salt, expectedHash := parts[0], parts[1], parts[2], parts[3]
-	if algorithm != "pbkdf2_sha256" {
-		return false, fmt.Errorf("unsupported algorithm: %s", algorithm)
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func GetSessionID(c *gin.Context) (int64, error) {
+	value, exists := c.Get("session_id")
+	if !exists {
+		return 0, errors.New("session is not resolved")
 	}
 
-	iterations, err := strconv.Atoi(iterStr)
-	if err != nil {
-		return false, fmt.Errorf("invalid iteration count: %s", iterStr)
+	sessionID, ok := value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected session id type: %T", value)
 	}
 
-	saltBytes := []byte(salt)
-	expectedHashBytes, err := base64.StdEncoding.DecodeString(expectedHash)
-	if err != nil {
-		return false, fmt.Errorf("invalid hash encoding: %v", err)
+	return sessionID, nil
+}
+
+// IsMFAVerified reports whether the request comes from a session, or an API
+// key created in a session, that passed a second factor.
+func IsMFAVerified(c *gin.Context) bool {
+	return c.GetBool("mfa_verified")
+}
+
+// GetAPIKeyProjectID returns the project an API key is limited to, if the
+// request was authenticated with such a key.
+func GetAPIKeyProjectID(c *gin.Context) (int64, bool) {
+	value, exists := c.Get("api_key_project_id")
+	if !exists {
+		return 0, false
+	}
+	projectID, ok := value.(int64)
+	return projectID, ok
+}
+
+func GetUserID(c *gin.Context) (int64, error) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, errors.New("user is not authenticated")
 	}
 
-	derivedKey := pbkdf2.Key([]byte(password), saltBytes, iterations, len(expectedHashBytes), sha256.New)
+	userID, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected user id type: %T", value)
+	}
+
+	return strconv.ParseInt(userID, 10, 64)
+}
 
-	return hmac.Equal(derivedKey, expectedHashBytes), nil
+func randomString(length int) (string, error) {
+	result := make([]byte, length)
+	for i := range result {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(saltChars))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random string: %w", err)
+		}
+		result[i] = saltChars[n.Int64()]
+	}
+	return string(result), nil
 }
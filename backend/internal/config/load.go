@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// NewParser returns a go-flags parser over cfg. Help shows the current values
+// of cfg as defaults, so pass the result of Default.
+func NewParser(cfg *Config) *flags.Parser {
+	parser := flags.NewParser(cfg, flags.Default)
+	parser.SubcommandsOptional = true
+	showDefaults(parser.Command.Group)
+	return parser
+}
+
+func showDefaults(group *flags.Group) {
+	for _, option := range group.Options() {
+		var mask string
+		switch value := option.Value().(type) {
+		case []string:
+			mask = strings.Join(value, ",")
+		case time.Duration:
+			if value != 0 {
+				mask = value.String()
+			}
+		case int:
+			if value != 0 {
+				mask = fmt.Sprint(value)
+			}
+		case fmt.Stringer:
+			mask = value.String()
+		case string:
+			mask = value
+		}
+		if mask != "" {
+			option.DefaultMask = mask
+		}
+	}
+	for _, child := range group.Groups() {
+		showDefaults(child)
+	}
+}
+
+// Parse fills cfg from the YAML file named by --config or CONFIG_FILE, then
+// from the environment and args on top of it. Secret files are read and the
+// result is validated before the selected command runs; without a command
+// the server settings are required too.
+func Parse(parser *flags.Parser, cfg *Config, args []string) error {
+	if err := loadFile(cfg, args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	parser.CommandHandler = func(cmd flags.Commander, args []string) error {
+		if err := cfg.loadSecrets(); err != nil {
+			return err
+		}
+		if err := cfg.Validate(cmd == nil); err != nil {
+			return err
+		}
+		if cmd == nil {
+			return nil
+		}
+		return cmd.Execute(args)
+	}
+	_, err := parser.ParseArgs(args)
+	return err
+}
+
+// loadFile finds the file name with a parser of its own, because the main
+// parse must run after the file has been read.
+func loadFile(cfg *Config, args []string) error {
+	var opts struct {
+		File string `long:"config" env:"CONFIG_FILE"`
+	}
+	if _, err := flags.NewParser(&opts, flags.IgnoreUnknown).ParseArgs(args); err != nil {
+		return err
+	}
+	if opts.File == "" {
+		return nil
+	}
+
+	file, err := os.Open(opts.File)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", opts.File, err)
+	}
+	return nil
+}
+
+// Print writes cfg in the format of the --config file, with secrets redacted.
+func Print(w io.Writer, cfg *Config) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to print config: %w", err)
+	}
+	return encoder.Close()
+}
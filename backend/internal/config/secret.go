@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// Secret is a setting that never shows up in printed configuration or logs.
+// Convert it with string() where the value is needed.
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// DSN is a database connection URL that prints with its password hidden.
+type DSN string
+
+func (d DSN) String() string {
+	u, err := url.Parse(string(d))
+	if err != nil || u.Scheme == "" {
+		// Key=value connection strings are hidden as a whole.
+		return Secret(d).String()
+	}
+	return u.Redacted()
+}
+
+func (d DSN) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// readSecretFile returns the contents of a file mounted by docker or
+// kubernetes secrets, without the trailing newline editors add.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// loadSecrets replaces secrets with the contents of their files. A file
+// wins over the value given directly.
+func (c *Config) loadSecrets() error {
+	if c.DB.DSNFile != "" {
+		dsn, err := readSecretFile(c.DB.DSNFile)
+		if err != nil {
+			return err
+		}
+		c.DB.DSN = DSN(dsn)
+	}
+
+	secrets := []struct {
+		path  string
+		value *Secret
+	}{
+		{c.OIDC.ClientSecretFile, &c.OIDC.ClientSecret},
+		{c.Projects.ShareLinkSecretFile, &c.Projects.ShareLinkSecret},
+		{c.Mail.SMTPPasswordFile, &c.Mail.SMTPPassword},
+	}
+	for _, secret := range secrets {
+		if secret.path == "" {
+			continue
+		}
+		value, err := readSecretFile(secret.path)
+		if err != nil {
+			return err
+		}
+		*secret.value = Secret(value)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"3d-backend/internal/cors"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// minSecretLength keeps HMAC keys out of brute-force range.
+const minSecretLength = 32
+
+// Validate reports every invalid setting at once. Settings that only the HTTP
+// server needs are required when server is set, so commands such as migrate
+// run with just a database.
+func (c *Config) Validate(server bool) error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	_, _, err := net.SplitHostPort(c.Server.Addr)
+	check(err == nil, "listen: %q is not a host:port address", c.Server.Addr)
+	for _, proxy := range c.Server.TrustedProxies {
+		check(isIPOrCIDR(proxy), "trusted-proxy: %q is not an IP address or CIDR", proxy)
+	}
+
+	check(oneOf(c.Log.Format, "text", "json"), "log-format: %q is not text or json", c.Log.Format)
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log-level: %q is not debug, info, warn or error", c.Log.Level)
+
+	if c.Tracing.Endpoint != "" {
+		check(isAbsoluteURL(c.Tracing.Endpoint), "otlp-endpoint: %q is not an absolute URL", c.Tracing.Endpoint)
+		check(c.Tracing.ServiceName != "", "service-name: required with otlp-endpoint")
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "trace-sample-ratio: %v is not between 0 and 1", c.Tracing.SampleRatio)
+
+	check(c.DB.DSN != "", "db: connection URL is required")
+	check(c.DB.MaxOpenConns > 0, "db-max-open-conns: must be positive")
+	check(c.DB.MaxIdleConns > 0 && c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db-max-idle-conns: must be positive and at most db-max-open-conns")
+	check(c.DB.ConnMaxLifetime >= 0, "db-conn-max-lifetime: must not be negative, 0 keeps connections forever")
+	check(c.DB.ConnMaxIdleTime >= 0, "db-conn-max-idle-time: must not be negative, 0 keeps idle connections forever")
+
+	ttls := []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"read-header-timeout", c.Server.ReadHeaderTimeout},
+		{"read-timeout", c.Server.ReadTimeout},
+		{"write-timeout", c.Server.WriteTimeout},
+		{"idle-timeout", c.Server.IdleTimeout},
+		{"shutdown-timeout", c.Server.ShutdownTimeout},
+		{"db-query-timeout", c.DB.QueryTimeout},
+		{"access-token-ttl", c.Auth.AccessTokenTTL},
+		{"refresh-token-ttl", c.Auth.RefreshTokenTTL},
+		{"password-reset-token-ttl", c.Auth.PasswordResetTokenTTL},
+		{"mfa-challenge-ttl", c.Auth.MFAChallengeTTL},
+		{"oidc-state-ttl", c.OIDC.StateTTL},
+		{"edit-lock-ttl", c.Projects.EditLockTTL},
+	}
+	for _, t := range ttls {
+		check(t.ttl > 0, "%s: must be positive, got %s", t.name, t.ttl)
+	}
+	check(c.Auth.AccessTokenTTL < c.Auth.RefreshTokenTTL, "access-token-ttl: must be shorter than refresh-token-ttl")
+
+	if c.Auth.PasswordResetURL != "" {
+		check(isAbsoluteURL(c.Auth.PasswordResetURL), "password-reset-url: %q is not an absolute URL", c.Auth.PasswordResetURL)
+	}
+	check(c.Auth.TOTPIssuer != "" && !strings.Contains(c.Auth.TOTPIssuer, ":"), "totp-issuer: must be set and must not contain a colon")
+
+	if c.OIDC.Issuer != "" {
+		check(isAbsoluteURL(c.OIDC.Issuer), "oidc-issuer: %q is not an absolute URL", c.OIDC.Issuer)
+		check(c.OIDC.ClientID != "", "oidc-client-id: required with oidc-issuer")
+		check(isAbsoluteURL(c.OIDC.RedirectURL), "oidc-redirect-url: required with oidc-issuer and must be an absolute URL")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		err := cors.ValidateOrigin(origin)
+		check(err == nil, "cors-allowed-origin: %v", err)
+		check(origin != "*" || !c.CORS.AllowCredentials, "cors-allow-credentials: not allowed with origin *")
+	}
+	check(c.CORS.MaxAge >= 0, "cors-max-age: must not be negative")
+
+	check(oneOf(c.RateLimit.Backend, "memory", "postgres"), "rate-limit-backend: %q is not memory or postgres", c.RateLimit.Backend)
+	check(c.RateLimit.RegisterPerHour > 0, "register-limit: must be positive")
+	check(c.RateLimit.PasswordResetPerHour > 0, "password-reset-limit: must be positive")
+	check(c.RateLimit.SharePerHour > 0, "share-limit: must be positive")
+
+	check(oneOf(c.Mail.Backend, "smtp", "file", "log"), "mail-backend: %q is not smtp, file or log", c.Mail.Backend)
+	switch c.Mail.Backend {
+	case "smtp":
+		check(c.Mail.SMTPHost != "", "smtp-host: required with the smtp mail backend")
+		check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort < 65536, "smtp-port: %d is not a port", c.Mail.SMTPPort)
+	case "file":
+		check(c.Mail.Dir != "", "mail-dir: required with the file mail backend")
+	}
+
+	if server {
+		check(c.Auth.JWTKeysDir != "", "jwt-keys-dir: required to sign tokens")
+		check(len(c.Projects.ShareLinkSecret) >= minSecretLength, "share-link-secret: at least %d characters required", minSecretLength)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(problems...))
+	}
+	return nil
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func isIPOrCIDR(raw string) bool {
+	if net.ParseIP(raw) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(raw)
+	return err == nil
+}
+
+func oneOf(value string, choices ...string) bool {
+	for _, choice := range choices {
+		if value == choice {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is the CORS policy of the API. Allowed methods are not configured:
+// a preflight is answered with the methods registered for the requested
+// route.
+type Config struct {
+	// AllowedOrigins are scheme://host[:port] entries. The host may start
+	// with *. to allow every subdomain, and * alone allows any origin.
+	AllowedOrigins []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies. It cannot be combined
+	// with *, because the origin has to be echoed for it.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+type origin struct {
+	scheme string
+	// host is the part after *. for wildcard origins.
+	host     string
+	port     string
+	wildcard bool
+}
+
+// ValidateOrigin reports whether raw can be used in AllowedOrigins.
+func ValidateOrigin(raw string) error {
+	if raw == "*" {
+		return nil
+	}
+	_, err := parseOrigin(raw)
+	return err
+}
+
+func parseOrigin(raw string) (origin, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return origin{}, fmt.Errorf("invalid origin %q, expected scheme://host[:port]", raw)
+	}
+
+	o := origin{scheme: strings.ToLower(u.Scheme), host: strings.ToLower(u.Hostname()), port: u.Port()}
+	if rest, ok := strings.CutPrefix(o.host, "*."); ok {
+		o.host, o.wildcard = rest, true
+	}
+	if o.host == "" || strings.Contains(o.host, "*") {
+		return origin{}, fmt.Errorf("invalid origin %q, * is only allowed as the first label", raw)
+	}
+	return o, nil
+}
+
+func (o origin) matches(requested origin) bool {
+	if o.scheme != requested.scheme || o.port != requested.port {
+		return false
+	}
+	if o.wildcard {
+		return strings.HasSuffix(requested.host, "."+o.host)
+	}
+	return o.host == requested.host
+}
+
+// Policy answers preflight requests and adds CORS headers to responses.
+type Policy struct {
+	anyOrigin        bool
+	origins          []origin
+	allowedHeaders   string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+
+	engine     *gin.Engine
+	routesOnce sync.Once
+	routes     []route
+}
+
+func New(cfg Config) (*Policy, error) {
+	p := &Policy{
+		allowedHeaders:   strings.Join(cfg.AllowedHeaders, ", "),
+		exposedHeaders:   strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+		maxAge:           strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, raw := range cfg.AllowedOrigins {
+		if raw == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		o, err := parseOrigin(raw)
+		if err != nil {
+			return nil, err
+		}
+		p.origins = append(p.origins, o)
+	}
+	if p.anyOrigin && p.allowCredentials {
+		return nil, errors.New("credentials cannot be allowed for any origin")
+	}
+	return p, nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for the request
+// origin, or an empty string when the origin is not allowed.
+func (p *Policy) allowOrigin(raw string) string {
+	if p.anyOrigin {
+		return "*"
+	}
+	requested, err := parseOrigin(raw)
+	if err != nil || requested.wildcard {
+		return ""
+	}
+	for _, o := range p.origins {
+		if o.matches(requested) {
+			return raw
+		}
+	}
+	return ""
+}
+
+// Middleware applies the policy. It has to be installed with engine.Use, so
+// it also sees preflights for which no OPTIONS route exists. The routes are
+// read from engine on the first request, after all of them are registered.
+func (p *Policy) Middleware(engine *gin.Engine) gin.HandlerFunc {
+	p.engine = engine
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		requestOrigin := c.GetHeader("Origin")
+		if !p.anyOrigin {
+			header.Add("Vary", "Origin")
+		}
+		if requestOrigin == "" {
+			c.Next()
+			return
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			p.preflight(c, requestOrigin)
+			return
+		}
+
+		if allowed := p.allowOrigin(requestOrigin); allowed != "" {
+			header.Set("Access-Control-Allow-Origin", allowed)
+			if p.allowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if p.exposedHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", p.exposedHeaders)
+			}
+		}
+		c.Next()
+	}
+}
+
+// preflight always answers 204. Without the Allow headers the browser
+// refuses the actual request.
+func (p *Policy) preflight(c *gin.Context, requestOrigin string) {
+	header := c.Writer.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	allowed := p.allowOrigin(requestOrigin)
+	methods := p.methods(c.Request.URL.Path)
+	if allowed == "" || !contains(methods, c.GetHeader("Access-Control-Request-Method")) {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", allowed)
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if p.allowedHeaders != "" {
+		header.Set("Access-Control-Allow-Headers", p.allowedHeaders)
+	}
+	if p.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	header.Set("Access-Control-Max-Age", p.maxAge)
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
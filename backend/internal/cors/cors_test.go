@@ -0,0 +1,207 @@
+package cors
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testEngine(t *testing.T, cfg Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	policy, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(policy.Middleware(r))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/project/project-details", ok)
+	r.POST("/project/create-project", ok)
+	r.GET("/project/:project_id/members", ok)
+	r.POST("/project/:project_id/members", ok)
+	r.DELETE("/project/:project_id/members/:user_id", ok)
+	r.GET("/files/*path", ok)
+	return r
+}
+
+var testConfig = Config{
+	AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org", "http://localhost:3000"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type"},
+	ExposedHeaders:   []string{"X-Request-ID"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func TestActualRequest(t *testing.T) {
+	r := testEngine(t, testConfig)
+
+	tests := []struct {
+		name      string
+		origin    string
+		wantAllow string
+	}{
+		{"no origin", "", ""},
+		{"exact origin", "https://app.example.com", "https://app.example.com"},
+		{"origin with port", "http://localhost:3000", "http://localhost:3000"},
+		{"other port", "http://localhost:3001", ""},
+		{"other scheme", "http://app.example.com", ""},
+		{"other host", "https://evil.example.com", ""},
+		{"host is a suffix", "https://evilapp.example.com", ""},
+		{"host case", "https://APP.example.com", "https://APP.example.com"},
+		{"subdomain", "https://a.example.org", "https://a.example.org"},
+		{"nested subdomain", "https://a.b.example.org", "https://a.b.example.org"},
+		{"wildcard apex", "https://example.org", ""},
+		{"wildcard suffix without dot", "https://evilexample.org", ""},
+		{"wildcard origin", "https://*.example.org", ""},
+		{"null origin", "null", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/project/project-details", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			header := w.Header()
+			if got := header.Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantAllow)
+			}
+			if !slices.Contains(header.Values("Vary"), "Origin") {
+				t.Errorf("Vary = %q, want Origin", header.Values("Vary"))
+			}
+
+			wantCredentials, wantExposed := "", ""
+			if tt.wantAllow != "" {
+				wantCredentials, wantExposed = "true", "X-Request-ID"
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Errorf("Allow-Credentials = %q, want %q", got, wantCredentials)
+			}
+			if got := header.Get("Access-Control-Expose-Headers"); got != wantExposed {
+				t.Errorf("Expose-Headers = %q, want %q", got, wantExposed)
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	r := testEngine(t, testConfig)
+
+	tests := []struct {
+		name        string
+		origin      string
+		path        string
+		method      string
+		wantMethods string
+	}{
+		{"get route", "https://app.example.com", "/project/project-details", "GET", "GET, OPTIONS"},
+		{"post route", "https://app.example.com", "/project/create-project", "POST", "POST, OPTIONS"},
+		{"method not on route", "https://app.example.com", "/project/create-project", "DELETE", ""},
+		{"params", "https://app.example.com", "/project/7/members", "POST", "GET, POST, OPTIONS"},
+		{"nested params", "https://app.example.com", "/project/7/members/3", "DELETE", "DELETE, OPTIONS"},
+		{"catch-all", "https://app.example.com", "/files/a/b.png", "GET", "GET, OPTIONS"},
+		{"unknown route", "https://app.example.com", "/nowhere", "GET", ""},
+		{"wildcard subdomain", "https://a.example.org", "/project/project-details", "GET", "GET, OPTIONS"},
+		{"denied origin", "https://evil.example.com", "/project/project-details", "GET", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			req.Header.Set("Access-Control-Request-Headers", "authorization")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+			}
+			header := w.Header()
+			if got := header.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+			for _, vary := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+				if !slices.Contains(header.Values("Vary"), vary) {
+					t.Errorf("Vary = %q, want %s", header.Values("Vary"), vary)
+				}
+			}
+
+			want := map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Headers":     "",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Max-Age":           "",
+			}
+			if tt.wantMethods != "" {
+				want = map[string]string{
+					"Access-Control-Allow-Origin":      tt.origin,
+					"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+					"Access-Control-Allow-Credentials": "true",
+					"Access-Control-Max-Age":           "600",
+				}
+			}
+			for name, value := range want {
+				if got := header.Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestAnyOrigin(t *testing.T) {
+	r := testEngine(t, Config{AllowedOrigins: []string{"*"}})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/project/project-details", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q, want *", got)
+	}
+	// The answer does not depend on the origin, so caches may share it.
+	if vary := w.Header().Values("Vary"); len(vary) != 0 {
+		t.Errorf("Vary = %q, want none", vary)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"origins", testConfig, false},
+		{"any origin", Config{AllowedOrigins: []string{"*"}}, false},
+		{"any origin with credentials", Config{AllowedOrigins: []string{"*"}, AllowCredentials: true}, true},
+		{"path", Config{AllowedOrigins: []string{"https://example.com/app"}}, true},
+		{"no scheme", Config{AllowedOrigins: []string{"example.com"}}, true},
+		{"query", Config{AllowedOrigins: []string{"https://example.com?a=1"}}, true},
+		{"user info", Config{AllowedOrigins: []string{"https://user@example.com"}}, true},
+		{"inner wildcard", Config{AllowedOrigins: []string{"https://a.*.example.com"}}, true},
+		{"bare wildcard host", Config{AllowedOrigins: []string{"https://*."}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
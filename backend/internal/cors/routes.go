@@ -0,0 +1,63 @@
+package cors
+
+import (
+	"strings"
+)
+
+// route is a registered path template with the methods served on it.
+type route struct {
+	segments []string
+	methods  []string
+}
+
+func (p *Policy) loadRoutes() {
+	index := map[string]int{}
+	for _, info := range p.engine.Routes() {
+		i, ok := index[info.Path]
+		if !ok {
+			i = len(p.routes)
+			index[info.Path] = i
+			p.routes = append(p.routes, route{segments: splitPath(info.Path)})
+		}
+		p.routes[i].methods = append(p.routes[i].methods, info.Method)
+	}
+}
+
+// methods lists the methods registered for the path, matching :param and
+// *wildcard segments like gin does.
+func (p *Policy) methods(path string) []string {
+	p.routesOnce.Do(p.loadRoutes)
+
+	segments := splitPath(path)
+	var methods []string
+	for _, r := range p.routes {
+		if !r.matches(segments) {
+			continue
+		}
+		for _, method := range r.methods {
+			if !contains(methods, method) {
+				methods = append(methods, method)
+			}
+		}
+	}
+	if len(methods) > 0 && !contains(methods, "OPTIONS") {
+		methods = append(methods, "OPTIONS")
+	}
+	return methods
+}
+
+func (r route) matches(segments []string) bool {
+	for i, pattern := range r.segments {
+		if strings.HasPrefix(pattern, "*") {
+			return true
+		}
+		if i >= len(segments) || (!strings.HasPrefix(pattern, ":") && pattern != segments[i]) {
+			return false
+		}
+	}
+	return len(segments) == len(r.segments)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
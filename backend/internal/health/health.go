@@ -0,0 +1,95 @@
+package health
+
+import (
+	"3d-backend/internal/logging"
+	"3d-backend/internal/migrate"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds each readiness check, so a hanging database makes the
+// probe fail instead of time out.
+const checkTimeout = 2 * time.Second
+
+type response struct {
+	Status string            `json:"status" example:"ok"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Handler answers liveness and readiness probes of docker-compose and
+// orchestrators.
+type Handler struct {
+	db       *sqlx.DB
+	migrator *migrate.Migrator
+	draining atomic.Bool
+}
+
+func NewHandler(db *sqlx.DB, migrator *migrate.Migrator) *Handler {
+	return &Handler{db: db, migrator: migrator}
+}
+
+// Drain makes the server report not ready, so load balancers stop sending
+// requests while the open ones finish.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live godoc
+// @Summary Проверка, что процесс жив
+// @Tags health
+// @Produce json
+// @Success 200 {object} response
+// @Router /healthz [get]
+func (h *Handler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, response{Status: "ok"})
+}
+
+// Ready godoc
+// @Summary Проверка готовности принимать запросы
+// @Description Проверяет соединение с базой и что все миграции применены.
+// @Tags health
+// @Produce json
+// @Success 200 {object} response
+// @Failure 503 {object} response
+// @Router /readyz [get]
+func (h *Handler) Ready(c *gin.Context) {
+	checks := map[string]string{
+		"database":   "ok",
+		"migrations": "ok",
+	}
+	ready := true
+	fail := func(check string, message string) {
+		checks[check] = message
+		ready = false
+	}
+
+	if h.draining.Load() {
+		fail("server", "shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), checkTimeout)
+	defer cancel()
+
+	// Probes are public, so the errors go to the log only.
+	if err := h.db.PingContext(ctx); err != nil {
+		logging.FromContext(ctx).Warn("Readiness check failed", "check", "database", "error", err)
+		fail("database", "unreachable")
+		fail("migrations", "unknown")
+	} else if pending, err := h.migrator.Pending(ctx); err != nil {
+		logging.FromContext(ctx).Warn("Readiness check failed", "check", "migrations", "error", err)
+		fail("migrations", "check failed")
+	} else if len(pending) > 0 {
+		fail("migrations", fmt.Sprintf("%d pending, run migrate up", len(pending)))
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, response{Status: "unavailable", Checks: checks})
+		return
+	}
+	c.JSON(http.StatusOK, response{Status: "ok", Checks: checks})
+}
@@ -0,0 +1,13 @@
+package mail
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers outgoing mail. SMTPSender is used in production, FileSender
+// and LogSender stand in for it in development and tests.
+type Sender interface {
+	Send(msg Message) error
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+// registry holds the metrics of this service only, so libraries registering
+// on the global registry do not end up in /metrics.
+var registry = prometheus.NewRegistry()
+
+var factory = promauto.With(registry)
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// SignIns counts sign-in attempts by method (password, totp, oidc) and
+	// result (success, failure, throttled). Success means tokens were issued;
+	// a password that leads to a second factor is not counted by itself.
+	SignIns = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_sign_ins_total",
+		Help: "Sign-in attempts by method and result.",
+	}, []string{"method", "result"})
+
+	// ObjectsCreated counts created projects, buildings and playgrounds.
+	ObjectsCreated = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "objects_created_total",
+		Help: "Created objects by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB exports the connection pool statistics of db as go_sql_*
+// metrics.
+func RegisterDB(db *sqlx.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db.DB, name))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
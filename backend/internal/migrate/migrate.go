@@ -0,0 +1,409 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID serialises migration runs of several instances.
+const lockID = 3_180_042
+
+var (
+	ErrIrreversible    = errors.New("migration has no down script")
+	ErrManagedByDjango = errors.New("migration is part of the schema managed by Django")
+)
+
+// djangoHeader starts the lines of an up script that name the Django
+// migration it mirrors, e.g. "-- django: projects 0002_editlock".
+const djangoHeader = "-- django: "
+
+// DjangoMigration is a migration of the Django admin as recorded in
+// django_migrations.
+type DjangoMigration struct {
+	App  string
+	Name string
+}
+
+// Migration is a pair of scripts named NNNN_name.up.sql and NNNN_name.down.sql.
+// Down is empty for migrations that cannot be reverted. Django lists the
+// migrations of the admin the scripts mirror: they are skipped where Django
+// applied them already and recorded for Django where they ran.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	Django  []DjangoMigration
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads the embedded migrations in version order.
+func Load() ([]Migration, error) {
+	names, err := fs.Glob(files, "sql/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, name := range names {
+		base := path.Base(name)
+		stem, direction, found := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		versionPart, migrationName, ok := strings.Cut(stem, "_")
+		if !found || !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name: %s", base)
+		}
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", base)
+		}
+
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration: %w", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: migrationName}
+			byVersion[version] = m
+		}
+		if m.Name != migrationName {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, migrationName)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+			m.Django, err = parseDjangoHeader(m.Up)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", base, err)
+			}
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func parseDjangoHeader(script string) ([]DjangoMigration, error) {
+	var mirrored []DjangoMigration
+	for _, line := range strings.Split(script, "\n") {
+		rest, found := strings.CutPrefix(line, djangoHeader)
+		if !found {
+			continue
+		}
+		app, name, ok := strings.Cut(strings.TrimSpace(rest), " ")
+		if !ok || app == "" || name == "" {
+			return nil, fmt.Errorf("invalid django header: %q", line)
+		}
+		mirrored = append(mirrored, DjangoMigration{App: app, Name: name})
+	}
+	return mirrored, nil
+}
+
+// Migrator applies the embedded migrations and records them in
+// go_schema_migrations. Every migration runs in its own transaction.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS go_schema_migrations (
+			version bigint NOT NULL PRIMARY KEY,
+			name varchar(255) NOT NULL,
+			applied_at timestamp with time zone NOT NULL
+		);
+	`
+	_, err := m.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied() (map[int64]time.Time, error) {
+	rows := []struct {
+		Version   int64     `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}{}
+	err := m.db.Select(&rows, `SELECT version, applied_at FROM go_schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// Status lists every known migration and when it was applied.
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending lists the migrations that are not applied yet. Unlike Status it
+// never writes, so it is safe to call from health checks.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	var exists bool
+	err := m.db.GetContext(ctx, &exists, `SELECT to_regclass('go_schema_migrations') IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for migrations table: %w", err)
+	}
+	if !exists {
+		return m.migrations, nil
+	}
+
+	var versions []int64
+	err = m.db.SelectContext(ctx, &versions, `SELECT version FROM go_schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		ran, err := m.run(migration, true)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return done, fmt.Errorf("%d_%s: %w", migration.Version, migration.Name, ErrIrreversible)
+		}
+		if _, err := m.run(migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// run applies or reverts one migration. It reports false when another
+// instance got there first.
+func (m *Migrator) run(migration Migration, up bool) (bool, error) {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, lockID)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+
+	var appliedAt time.Time
+	err = tx.Get(&appliedAt, `SELECT applied_at FROM go_schema_migrations WHERE version = $1`, migration.Version)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to check migration: %w", err)
+	}
+	isApplied := err == nil
+	if isApplied == up {
+		return false, nil
+	}
+
+	script, record := migration.Up, `INSERT INTO go_schema_migrations (version, name, applied_at) VALUES ($1, $2, now())`
+	if !up {
+		script, record = migration.Down, `DELETE FROM go_schema_migrations WHERE version = $1 AND name = $2`
+	}
+
+	if len(migration.Django) > 0 {
+		script, err = syncDjango(tx, migration, script, up)
+		if err != nil {
+			return false, fmt.Errorf("%d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if script != "" {
+		_, err = tx.Exec(script)
+		if err != nil {
+			return false, fmt.Errorf("failed to run migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err = tx.Exec(record, migration.Version, migration.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// syncDjango keeps django_migrations in step with a migration that mirrors
+// Django ones and returns the script that still has to run. Going up, the
+// script is skipped when Django applied its migrations already; otherwise
+// they are recorded as applied, so Django's migrate does not repeat them.
+// Going down, they are removed again, unless Django applied later migrations
+// of the same app that depend on them.
+func syncDjango(tx *sqlx.Tx, migration Migration, script string, up bool) (string, error) {
+	query := `
+		CREATE TABLE IF NOT EXISTS django_migrations (
+			id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+			app varchar(255) NOT NULL,
+			name varchar(255) NOT NULL,
+			applied timestamp with time zone NOT NULL
+		);
+	`
+	_, err := tx.Exec(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to create django migrations table: %w", err)
+	}
+
+	var recorded int
+	for _, dm := range migration.Django {
+		var exists bool
+		err := tx.Get(&exists, `SELECT EXISTS (SELECT 1 FROM django_migrations WHERE app = $1 AND name = $2)`, dm.App, dm.Name)
+		if err != nil {
+			return "", fmt.Errorf("failed to check django migration: %w", err)
+		}
+		if exists {
+			recorded++
+		}
+	}
+	if recorded > 0 && recorded < len(migration.Django) {
+		return "", fmt.Errorf("%w: Django applied only some of the mirrored migrations, run its migrate first", ErrManagedByDjango)
+	}
+
+	if up {
+		if recorded > 0 {
+			return "", nil
+		}
+		for _, dm := range migration.Django {
+			_, err := tx.Exec(`INSERT INTO django_migrations (app, name, applied) VALUES ($1, $2, now())`, dm.App, dm.Name)
+			if err != nil {
+				return "", fmt.Errorf("failed to record django migration: %w", err)
+			}
+		}
+		return script, nil
+	}
+
+	for _, dm := range migration.Django {
+		var later []string
+		err := tx.Select(&later, `SELECT name FROM django_migrations WHERE app = $1 AND name > $2 ORDER BY name`, dm.App, dm.Name)
+		if err != nil {
+			return "", fmt.Errorf("failed to check django migrations: %w", err)
+		}
+		if len(later) > 0 && !mirrors(migration, dm.App, later) {
+			return "", fmt.Errorf("%w: %s %s is needed by %s", ErrManagedByDjango, dm.App, dm.Name, later[0])
+		}
+	}
+	for _, dm := range migration.Django {
+		_, err := tx.Exec(`DELETE FROM django_migrations WHERE app = $1 AND name = $2`, dm.App, dm.Name)
+		if err != nil {
+			return "", fmt.Errorf("failed to remove django migration: %w", err)
+		}
+	}
+	return script, nil
+}
+
+// mirrors reports whether the migration mirrors every named migration of app.
+func mirrors(migration Migration, app string, names []string) bool {
+	for _, name := range names {
+		if !slices.Contains(migration.Django, DjangoMigration{App: app, Name: name}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,258 @@
+package migrate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("%d_%s: want version %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("%d_%s: no down script", m.Version, m.Name)
+		}
+	}
+}
+
+// TestDjangoHeaders checks that every migration of the admin apps is mirrored
+// exactly once and in the order Django applies them.
+func TestDjangoHeaders(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mirrored := map[string][]string{}
+	for _, m := range migrations {
+		for _, dm := range m.Django {
+			mirrored[dm.App] = append(mirrored[dm.App], dm.Name)
+		}
+	}
+
+	for _, app := range []string{"projects", "accounts"} {
+		files, err := filepath.Glob(filepath.Join("..", "..", "..", "adminka", app, "migrations", "0*.py"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Skip("Django admin sources not found")
+		}
+
+		var names []string
+		for _, file := range files {
+			names = append(names, strings.TrimSuffix(filepath.Base(file), ".py"))
+		}
+		slices.Sort(names)
+
+		if !slices.Equal(mirrored[app], names) {
+			t.Errorf("%s: mirrored %v, want %v", app, mirrored[app], names)
+		}
+	}
+}
+
+func TestParseDjangoHeader(t *testing.T) {
+	got, err := parseDjangoHeader("-- django: projects 0002_editlock\n-- other comment\nCREATE TABLE x ();\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DjangoMigration{{App: "projects", Name: "0002_editlock"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := parseDjangoHeader("-- django: projects\n"); err == nil {
+		t.Error("header without a migration name accepted")
+	}
+}
+
+// testDB connects to TEST_DB_DSN with a fresh schema as search path. The
+// schema is dropped after the test.
+func testDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_migrate_" + hex.EncodeToString(suffix)
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		dsn += " search_path=" + schema
+	} else {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		dsn = u.String()
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestUpAndDownOnEmptyDatabase(t *testing.T) {
+	db := testDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(m.migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(m.migrations))
+	}
+
+	var want int
+	for _, migration := range m.migrations {
+		want += len(migration.Django)
+	}
+	var recorded int
+	if err := db.Get(&recorded, `SELECT count(*) FROM django_migrations`); err != nil {
+		t.Fatal(err)
+	}
+	if recorded != want {
+		t.Errorf("django_migrations has %d rows, want %d", recorded, want)
+	}
+
+	reverted, err := m.Down(len(m.migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(m.migrations) {
+		t.Fatalf("reverted %d migrations, want %d", len(reverted), len(m.migrations))
+	}
+	if err := db.Get(&recorded, `SELECT count(*) FROM django_migrations`); err != nil {
+		t.Fatal(err)
+	}
+	if recorded != 0 {
+		t.Errorf("django_migrations has %d rows after down, want 0", recorded)
+	}
+}
+
+// TestUpOnDjangoBaseline starts from a database Django migrated to the first
+// projects migration only, with a project in it.
+func TestUpOnDjangoBaseline(t *testing.T) {
+	db := testDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, migration := range m.migrations[:2] {
+		if _, err := db.Exec(migration.Up); err != nil {
+			t.Fatal(err)
+		}
+	}
+	baseline := `
+		CREATE TABLE django_migrations (
+			id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+			app varchar(255) NOT NULL,
+			name varchar(255) NOT NULL,
+			applied timestamp with time zone NOT NULL
+		);
+	`
+	if _, err := db.Exec(baseline); err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range m.migrations[:2] {
+		for _, dm := range migration.Django {
+			_, err := db.Exec(`INSERT INTO django_migrations (app, name, applied) VALUES ($1, $2, now())`, dm.App, dm.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	fixtures := []string{
+		`INSERT INTO auth_user (id, password, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined)
+		VALUES (1, '', false, 'alice', '', '', '', false, true, now())`,
+		`INSERT INTO auth_user (id, password, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined)
+		VALUES (2, '', false, 'bob', '', '', '', false, true, now())`,
+		`INSERT INTO projects_project (id, name) VALUES (1, 'Site'), (2, 'Shed')`,
+		`INSERT INTO projects_project_user (project_id, user_id) VALUES (1, 1), (2, 2), (1, 2)`,
+	}
+	for _, fixture := range fixtures {
+		if _, err := db.Exec(fixture); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var role string
+	if err := db.Get(&role, `SELECT role FROM projects_project_user WHERE project_id = 1 AND user_id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if role != "owner" {
+		t.Errorf("role = %q, want owner", role)
+	}
+
+	// Each owner gets an organisation of their own. Bob, added to alice's
+	// project after her, joins her organisation as a member only.
+	var memberships []string
+	query := `
+		SELECT o.name || ': ' || u.username || ' ' || om.role || ' ' || string_agg(pr.name, ',' ORDER BY pr.id)
+		FROM projects_organisationmember om
+		JOIN projects_organisation o ON o.id = om.organisation_id
+		JOIN auth_user u ON u.id = om.user_id
+		JOIN projects_project pr ON pr.organisation_id = o.id
+		GROUP BY o.name, u.username, om.role
+		ORDER BY o.name, u.username
+	`
+	if err := db.Select(&memberships, query); err != nil {
+		t.Fatal(err)
+	}
+	wantMemberships := []string{"alice: alice admin Site", "alice: bob member Site", "bob: bob admin Shed"}
+	if !slices.Equal(memberships, wantMemberships) {
+		t.Errorf("organisation memberships = %v, want %v", memberships, wantMemberships)
+	}
+
+	var latest []string
+	err = db.Select(&latest, `SELECT app || ' ' || max(name) FROM django_migrations WHERE app IN ('projects', 'accounts') GROUP BY app ORDER BY app`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"accounts 0007_two_factor", "projects 0008_organisation_require_2fa"}
+	if !slices.Equal(latest, want) {
+		t.Errorf("latest django migrations = %v, want %v", latest, want)
+	}
+}
@@ -0,0 +1,87 @@
+-- Tables of django.contrib.contenttypes and django.contrib.auth as Django 5.1
+-- leaves them after all of their migrations.
+-- django: contenttypes 0001_initial
+-- django: contenttypes 0002_remove_content_type_name
+-- django: auth 0001_initial
+-- django: auth 0002_alter_permission_name_max_length
+-- django: auth 0003_alter_user_email_max_length
+-- django: auth 0004_alter_user_username_opts
+-- django: auth 0005_alter_user_last_login_null
+-- django: auth 0006_require_contenttypes_0002
+-- django: auth 0007_alter_validators_add_error_messages
+-- django: auth 0008_alter_user_username_max_length
+-- django: auth 0009_alter_user_last_name_max_length
+-- django: auth 0010_alter_group_name_max_length
+-- django: auth 0011_update_proxy_permissions
+-- django: auth 0012_alter_user_first_name_max_length
+
+CREATE TABLE django_content_type (
+	id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	app_label varchar(100) NOT NULL,
+	model varchar(100) NOT NULL,
+	CONSTRAINT django_content_type_app_label_model_76bd3d3b_uniq UNIQUE (app_label, model)
+);
+
+CREATE TABLE auth_permission (
+	id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	name varchar(255) NOT NULL,
+	content_type_id integer NOT NULL,
+	codename varchar(100) NOT NULL,
+	CONSTRAINT auth_permission_content_type_id_codename_01ab375a_uniq UNIQUE (content_type_id, codename),
+	CONSTRAINT auth_permission_content_type_id_2f476e4b_fk_django_co FOREIGN KEY (content_type_id) REFERENCES django_content_type (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX auth_permission_content_type_id_2f476e4b ON auth_permission (content_type_id);
+
+CREATE TABLE auth_group (
+	id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	name varchar(150) NOT NULL UNIQUE
+);
+CREATE INDEX auth_group_name_a6ea08ec_like ON auth_group (name varchar_pattern_ops);
+
+CREATE TABLE auth_group_permissions (
+	id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	group_id integer NOT NULL,
+	permission_id integer NOT NULL,
+	CONSTRAINT auth_group_permissions_group_id_permission_id_0cd325b0_uniq UNIQUE (group_id, permission_id),
+	CONSTRAINT auth_group_permissions_group_id_b120cbf9_fk_auth_group_id FOREIGN KEY (group_id) REFERENCES auth_group (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT auth_group_permissio_permission_id_84c5c92e_fk_auth_perm FOREIGN KEY (permission_id) REFERENCES auth_permission (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX auth_group_permissions_group_id_b120cbf9 ON auth_group_permissions (group_id);
+CREATE INDEX auth_group_permissions_permission_id_84c5c92e ON auth_group_permissions (permission_id);
+
+CREATE TABLE auth_user (
+	id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	password varchar(128) NOT NULL,
+	last_login timestamp with time zone NULL,
+	is_superuser boolean NOT NULL,
+	username varchar(150) NOT NULL UNIQUE,
+	first_name varchar(150) NOT NULL,
+	last_name varchar(150) NOT NULL,
+	email varchar(254) NOT NULL,
+	is_staff boolean NOT NULL,
+	is_active boolean NOT NULL,
+	date_joined timestamp with time zone NOT NULL
+);
+CREATE INDEX auth_user_username_6821ab7c_like ON auth_user (username varchar_pattern_ops);
+
+CREATE TABLE auth_user_groups (
+	id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	user_id integer NOT NULL,
+	group_id integer NOT NULL,
+	CONSTRAINT auth_user_groups_user_id_group_id_94350c0c_uniq UNIQUE (user_id, group_id),
+	CONSTRAINT auth_user_groups_user_id_6a12ed8b_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT auth_user_groups_group_id_97559544_fk_auth_group_id FOREIGN KEY (group_id) REFERENCES auth_group (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX auth_user_groups_group_id_97559544 ON auth_user_groups (group_id);
+CREATE INDEX auth_user_groups_user_id_6a12ed8b ON auth_user_groups (user_id);
+
+CREATE TABLE auth_user_user_permissions (
+	id integer NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	user_id integer NOT NULL,
+	permission_id integer NOT NULL,
+	CONSTRAINT auth_user_user_permissions_user_id_permission_id_14a6b632_uniq UNIQUE (user_id, permission_id),
+	CONSTRAINT auth_user_user_permissions_user_id_a95ead1b_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT auth_user_user_permi_permission_id_1fbb5f2c_fk_auth_perm FOREIGN KEY (permission_id) REFERENCES auth_permission (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX auth_user_user_permissions_permission_id_1fbb5f2c ON auth_user_user_permissions (permission_id);
+CREATE INDEX auth_user_user_permissions_user_id_a95ead1b ON auth_user_user_permissions (user_id);
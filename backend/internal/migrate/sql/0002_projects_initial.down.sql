@@ -0,0 +1,4 @@
+DROP TABLE projects_building;
+DROP TABLE projects_playground;
+DROP TABLE projects_project_user;
+DROP TABLE projects_project;
@@ -0,0 +1,34 @@
+-- django: projects 0001_initial
+
+CREATE TABLE projects_project (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	name varchar(255) NOT NULL
+);
+
+CREATE TABLE projects_project_user (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	project_id bigint NOT NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT projects_project_user_project_id_user_id_5587fd22_uniq UNIQUE (project_id, user_id),
+	CONSTRAINT projects_project_use_project_id_3797356a_fk_projects_ FOREIGN KEY (project_id) REFERENCES projects_project (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_project_user_user_id_997e8e0d_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX projects_project_user_project_id_3797356a ON projects_project_user (project_id);
+CREATE INDEX projects_project_user_user_id_997e8e0d ON projects_project_user (user_id);
+
+CREATE TABLE projects_playground (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	coordinates jsonb NOT NULL,
+	project_id bigint NOT NULL UNIQUE,
+	CONSTRAINT projects_playground_project_id_1df145af_fk_projects_project_id FOREIGN KEY (project_id) REFERENCES projects_project (id) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE TABLE projects_building (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	coordinates jsonb NOT NULL,
+	floors integer NOT NULL,
+	floors_height double precision NOT NULL,
+	project_id bigint NOT NULL,
+	CONSTRAINT projects_building_project_id_a453120f_fk_projects_project_id FOREIGN KEY (project_id) REFERENCES projects_project (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX projects_building_project_id_a453120f ON projects_building (project_id);
@@ -0,0 +1,16 @@
+-- django: projects 0002_editlock
+
+CREATE TABLE projects_editlock (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	object_type varchar(32) NOT NULL,
+	object_id bigint NOT NULL,
+	acquired_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL,
+	project_id bigint NOT NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT unique_edit_lock UNIQUE (object_type, object_id),
+	CONSTRAINT projects_editlock_project_id_2fa5bc3e_fk_projects_project_id FOREIGN KEY (project_id) REFERENCES projects_project (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_editlock_user_id_4fdba1df_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX projects_editlock_project_id_2fa5bc3e ON projects_editlock (project_id);
+CREATE INDEX projects_editlock_user_id_4fdba1df ON projects_editlock (user_id);
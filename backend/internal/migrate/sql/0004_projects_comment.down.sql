@@ -0,0 +1,2 @@
+DROP TABLE projects_comment_mentions;
+DROP TABLE projects_comment;
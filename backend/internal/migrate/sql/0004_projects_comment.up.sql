@@ -0,0 +1,37 @@
+-- django: projects 0003_comment
+
+CREATE TABLE projects_comment (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	text text NOT NULL,
+	point_x double precision NULL,
+	point_y double precision NULL,
+	is_resolved boolean NOT NULL,
+	resolved_at timestamp with time zone NULL,
+	created_at timestamp with time zone NOT NULL,
+	author_id integer NOT NULL,
+	building_id bigint NULL,
+	parent_id bigint NULL,
+	project_id bigint NOT NULL,
+	resolved_by_id integer NULL,
+	CONSTRAINT projects_comment_author_id_eec213f3_fk_auth_user_id FOREIGN KEY (author_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_comment_building_id_524b15f1_fk_projects_building_id FOREIGN KEY (building_id) REFERENCES projects_building (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_comment_parent_id_a3fb47d3_fk_projects_comment_id FOREIGN KEY (parent_id) REFERENCES projects_comment (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_comment_project_id_220d4b34_fk_projects_project_id FOREIGN KEY (project_id) REFERENCES projects_project (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_comment_resolved_by_id_1f3db5f0_fk_auth_user_id FOREIGN KEY (resolved_by_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX projects_comment_author_id_eec213f3 ON projects_comment (author_id);
+CREATE INDEX projects_comment_building_id_524b15f1 ON projects_comment (building_id);
+CREATE INDEX projects_comment_parent_id_a3fb47d3 ON projects_comment (parent_id);
+CREATE INDEX projects_comment_project_id_220d4b34 ON projects_comment (project_id);
+CREATE INDEX projects_comment_resolved_by_id_1f3db5f0 ON projects_comment (resolved_by_id);
+
+CREATE TABLE projects_comment_mentions (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	comment_id bigint NOT NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT projects_comment_mentions_comment_id_user_id_b69075b0_uniq UNIQUE (comment_id, user_id),
+	CONSTRAINT projects_comment_men_comment_id_93d3255b_fk_projects_ FOREIGN KEY (comment_id) REFERENCES projects_comment (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_comment_mentions_user_id_e5d64d23_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX projects_comment_mentions_comment_id_93d3255b ON projects_comment_mentions (comment_id);
+CREATE INDEX projects_comment_mentions_user_id_e5d64d23 ON projects_comment_mentions (user_id);
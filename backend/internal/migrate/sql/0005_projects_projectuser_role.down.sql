@@ -0,0 +1 @@
+ALTER TABLE projects_project_user DROP COLUMN role;
@@ -0,0 +1,6 @@
+-- django: projects 0004_projectuser_role
+-- The through model only changes Django's state; the table is the one of the
+-- automatic many-to-many field. Existing members become owners.
+
+ALTER TABLE projects_project_user ADD COLUMN role varchar(16) DEFAULT 'owner' NOT NULL;
+ALTER TABLE projects_project_user ALTER COLUMN role DROP DEFAULT;
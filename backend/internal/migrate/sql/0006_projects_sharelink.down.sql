@@ -0,0 +1 @@
+DROP TABLE projects_sharelink;
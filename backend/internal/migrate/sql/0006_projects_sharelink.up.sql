@@ -0,0 +1,17 @@
+-- django: projects 0005_sharelink
+
+CREATE TABLE projects_sharelink (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	token_hash varchar(64) NOT NULL UNIQUE,
+	password varchar(128) NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NULL,
+	revoked_at timestamp with time zone NULL,
+	created_by_id integer NOT NULL,
+	project_id bigint NOT NULL,
+	CONSTRAINT projects_sharelink_created_by_id_f56a946a_fk_auth_user_id FOREIGN KEY (created_by_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_sharelink_project_id_64874927_fk_projects_project_id FOREIGN KEY (project_id) REFERENCES projects_project (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX projects_sharelink_created_by_id_f56a946a ON projects_sharelink (created_by_id);
+CREATE INDEX projects_sharelink_project_id_64874927 ON projects_sharelink (project_id);
+CREATE INDEX projects_sharelink_token_hash_a265bef7_like ON projects_sharelink (token_hash varchar_pattern_ops);
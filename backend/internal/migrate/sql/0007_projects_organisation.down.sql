@@ -0,0 +1,3 @@
+ALTER TABLE projects_project DROP COLUMN organisation_id;
+DROP TABLE projects_organisationmember;
+DROP TABLE projects_organisation;
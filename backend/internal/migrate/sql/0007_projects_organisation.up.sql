@@ -0,0 +1,59 @@
+-- django: projects 0006_organisation
+-- Every existing project moves into an organisation of its owner, who becomes
+-- the organisation's admin; the project's other users join as members, as in
+-- create_owner_organisations of the Django migration. A project with several
+-- owners goes to the one added first. Projects without users share an
+-- organisation without members.
+
+CREATE TABLE projects_organisation (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	name varchar(255) NOT NULL,
+	created_at timestamp with time zone NOT NULL
+);
+
+CREATE TABLE projects_organisationmember (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	role varchar(16) NOT NULL,
+	organisation_id bigint NOT NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT projects_organisationmem_organisation_id_user_id_1406bff2_uniq UNIQUE (organisation_id, user_id),
+	CONSTRAINT projects_organisatio_organisation_id_af9070ef_fk_projects_ FOREIGN KEY (organisation_id) REFERENCES projects_organisation (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT projects_organisationmember_user_id_3e4771cf_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX projects_organisationmember_organisation_id_af9070ef ON projects_organisationmember (organisation_id);
+CREATE INDEX projects_organisationmember_user_id_3e4771cf ON projects_organisationmember (user_id);
+
+ALTER TABLE projects_project ADD COLUMN organisation_id bigint NULL
+	CONSTRAINT projects_project_organisation_id_97360d51_fk_projects_ REFERENCES projects_organisation (id) DEFERRABLE INITIALLY DEFERRED;
+CREATE INDEX projects_project_organisation_id_97360d51 ON projects_project (organisation_id);
+
+CREATE TEMPORARY TABLE legacy_project_owner ON COMMIT DROP AS
+SELECT pr.id AS project_id, (
+	SELECT pu.user_id
+	FROM projects_project_user pu
+	WHERE pu.project_id = pr.id
+	ORDER BY pu.role <> 'owner', pu.id
+	LIMIT 1
+) AS user_id
+FROM projects_project pr;
+
+CREATE TEMPORARY TABLE legacy_organisation ON COMMIT DROP AS
+SELECT nextval(pg_get_serial_sequence('projects_organisation', 'id')) AS id, o.user_id
+FROM (SELECT DISTINCT user_id FROM legacy_project_owner) o;
+
+INSERT INTO projects_organisation (id, name, created_at)
+SELECT o.id, COALESCE(u.username, 'По умолчанию'), now()
+FROM legacy_organisation o
+LEFT JOIN auth_user u ON u.id = o.user_id;
+
+UPDATE projects_project pr
+SET organisation_id = o.id
+FROM legacy_project_owner po
+JOIN legacy_organisation o ON o.user_id IS NOT DISTINCT FROM po.user_id
+WHERE pr.id = po.project_id;
+
+INSERT INTO projects_organisationmember (role, organisation_id, user_id)
+SELECT DISTINCT CASE WHEN pu.user_id = o.user_id THEN 'admin' ELSE 'member' END, o.id, pu.user_id
+FROM projects_project_user pu
+JOIN legacy_project_owner po ON po.project_id = pu.project_id
+JOIN legacy_organisation o ON o.user_id IS NOT DISTINCT FROM po.user_id;
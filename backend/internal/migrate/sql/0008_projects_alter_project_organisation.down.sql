@@ -0,0 +1 @@
+ALTER TABLE projects_project ALTER COLUMN organisation_id DROP NOT NULL;
@@ -0,0 +1,3 @@
+-- django: projects 0007_alter_project_organisation
+
+ALTER TABLE projects_project ALTER COLUMN organisation_id SET NOT NULL;
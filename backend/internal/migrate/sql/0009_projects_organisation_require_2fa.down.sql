@@ -0,0 +1 @@
+ALTER TABLE projects_organisation DROP COLUMN require_2fa;
@@ -0,0 +1,4 @@
+-- django: projects 0008_organisation_require_2fa
+
+ALTER TABLE projects_organisation ADD COLUMN require_2fa boolean DEFAULT false NOT NULL;
+ALTER TABLE projects_organisation ALTER COLUMN require_2fa DROP DEFAULT;
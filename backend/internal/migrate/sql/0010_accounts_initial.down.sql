@@ -0,0 +1,2 @@
+DROP TABLE accounts_refreshtoken;
+DROP TABLE accounts_session;
@@ -0,0 +1,26 @@
+-- django: accounts 0001_initial
+
+CREATE TABLE accounts_session (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	user_agent varchar(512) NOT NULL,
+	ip_address varchar(64) NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	last_used_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL,
+	revoked_at timestamp with time zone NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT accounts_session_user_id_d5aaed01_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX accounts_session_user_id_d5aaed01 ON accounts_session (user_id);
+
+CREATE TABLE accounts_refreshtoken (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	token_hash varchar(64) NOT NULL UNIQUE,
+	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL,
+	used_at timestamp with time zone NULL,
+	session_id bigint NOT NULL,
+	CONSTRAINT accounts_refreshtoke_session_id_97916894_fk_accounts_ FOREIGN KEY (session_id) REFERENCES accounts_session (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX accounts_refreshtoken_session_id_97916894 ON accounts_refreshtoken (session_id);
+CREATE INDEX accounts_refreshtoken_token_hash_ba821f25_like ON accounts_refreshtoken (token_hash varchar_pattern_ops);
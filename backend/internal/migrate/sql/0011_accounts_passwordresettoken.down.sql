@@ -0,0 +1 @@
+DROP TABLE accounts_passwordresettoken;
@@ -0,0 +1,13 @@
+-- django: accounts 0002_passwordresettoken
+
+CREATE TABLE accounts_passwordresettoken (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	token_hash varchar(64) NOT NULL UNIQUE,
+	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL,
+	used_at timestamp with time zone NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT accounts_passwordresettoken_user_id_2789bc5c_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX accounts_passwordresettoken_token_hash_7479d5dc_like ON accounts_passwordresettoken (token_hash varchar_pattern_ops);
+CREATE INDEX accounts_passwordresettoken_user_id_2789bc5c ON accounts_passwordresettoken (user_id);
@@ -0,0 +1,3 @@
+DELETE FROM auth_group_permissions WHERE group_id IN (SELECT id FROM auth_group WHERE name = 'Users');
+DELETE FROM auth_user_groups WHERE group_id IN (SELECT id FROM auth_group WHERE name = 'Users');
+DELETE FROM auth_group WHERE name = 'Users';
@@ -0,0 +1,82 @@
+-- django: accounts 0003_default_group
+-- Content types and permissions of the projects app, which Django creates after
+-- migrate, and the default group with every existing user in it.
+
+INSERT INTO django_content_type (app_label, model)
+VALUES
+	('projects', 'project'),
+	('projects', 'building'),
+	('projects', 'playground'),
+	('projects', 'editlock'),
+	('projects', 'comment'),
+	('projects', 'projectuser'),
+	('projects', 'sharelink'),
+	('projects', 'organisation'),
+	('projects', 'organisationmember')
+ON CONFLICT (app_label, model) DO NOTHING;
+
+INSERT INTO auth_permission (name, content_type_id, codename)
+SELECT p.name, ct.id, p.codename
+FROM (VALUES
+	('project', 'add_project', 'Can add Проект'),
+	('project', 'change_project', 'Can change Проект'),
+	('project', 'delete_project', 'Can delete Проект'),
+	('project', 'view_project', 'Can view Проект'),
+	('building', 'add_building', 'Can add Здание'),
+	('building', 'change_building', 'Can change Здание'),
+	('building', 'delete_building', 'Can delete Здание'),
+	('building', 'view_building', 'Can view Здание'),
+	('playground', 'add_playground', 'Can add Площадка'),
+	('playground', 'change_playground', 'Can change Площадка'),
+	('playground', 'delete_playground', 'Can delete Площадка'),
+	('playground', 'view_playground', 'Can view Площадка'),
+	('editlock', 'add_editlock', 'Can add Блокировка'),
+	('editlock', 'change_editlock', 'Can change Блокировка'),
+	('editlock', 'delete_editlock', 'Can delete Блокировка'),
+	('editlock', 'view_editlock', 'Can view Блокировка'),
+	('comment', 'add_comment', 'Can add Комментарий'),
+	('comment', 'change_comment', 'Can change Комментарий'),
+	('comment', 'delete_comment', 'Can delete Комментарий'),
+	('comment', 'view_comment', 'Can view Комментарий'),
+	('projectuser', 'add_projectuser', 'Can add Участник проекта'),
+	('projectuser', 'change_projectuser', 'Can change Участник проекта'),
+	('projectuser', 'delete_projectuser', 'Can delete Участник проекта'),
+	('projectuser', 'view_projectuser', 'Can view Участник проекта'),
+	('sharelink', 'add_sharelink', 'Can add Публичная ссылка'),
+	('sharelink', 'change_sharelink', 'Can change Публичная ссылка'),
+	('sharelink', 'delete_sharelink', 'Can delete Публичная ссылка'),
+	('sharelink', 'view_sharelink', 'Can view Публичная ссылка'),
+	('organisation', 'add_organisation', 'Can add Организация'),
+	('organisation', 'change_organisation', 'Can change Организация'),
+	('organisation', 'delete_organisation', 'Can delete Организация'),
+	('organisation', 'view_organisation', 'Can view Организация'),
+	('organisationmember', 'add_organisationmember', 'Can add Участник организации'),
+	('organisationmember', 'change_organisationmember', 'Can change Участник организации'),
+	('organisationmember', 'delete_organisationmember', 'Can delete Участник организации'),
+	('organisationmember', 'view_organisationmember', 'Can view Участник организации')
+) AS p (model, codename, name)
+JOIN django_content_type ct ON ct.app_label = 'projects' AND ct.model = p.model
+ON CONFLICT (content_type_id, codename) DO NOTHING;
+
+WITH new_group AS (
+	INSERT INTO auth_group (name)
+	VALUES ('Users')
+	ON CONFLICT (name) DO NOTHING
+	RETURNING id
+), group_permissions AS (
+	INSERT INTO auth_group_permissions (group_id, permission_id)
+	SELECT g.id, p.id
+	FROM new_group g
+	CROSS JOIN auth_permission p
+	JOIN django_content_type ct ON ct.id = p.content_type_id
+	WHERE ct.app_label = 'projects' AND p.codename IN (
+		'view_project', 'add_project', 'add_building', 'change_building',
+		'add_playground', 'change_playground', 'change_editlock', 'view_comment', 'add_comment',
+		'change_comment', 'view_projectuser', 'add_projectuser', 'change_projectuser',
+		'delete_projectuser', 'view_sharelink', 'add_sharelink', 'change_sharelink'
+	)
+)
+INSERT INTO auth_user_groups (user_id, group_id)
+SELECT u.id, g.id
+FROM auth_user u
+CROSS JOIN new_group g;
@@ -0,0 +1,2 @@
+DROP TABLE accounts_oidcidentity;
+DROP TABLE accounts_oidcloginstate;
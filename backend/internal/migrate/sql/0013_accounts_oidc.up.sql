@@ -0,0 +1,22 @@
+-- django: accounts 0004_oidc
+
+CREATE TABLE accounts_oidcloginstate (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	state_hash varchar(64) NOT NULL UNIQUE,
+	code_verifier varchar(128) NOT NULL,
+	nonce varchar(128) NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL
+);
+CREATE INDEX accounts_oidcloginstate_state_hash_40273c36_like ON accounts_oidcloginstate (state_hash varchar_pattern_ops);
+
+CREATE TABLE accounts_oidcidentity (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	issuer varchar(255) NOT NULL,
+	subject varchar(255) NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT unique_oidc_identity UNIQUE (issuer, subject),
+	CONSTRAINT accounts_oidcidentity_user_id_eec83e9c_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX accounts_oidcidentity_user_id_eec83e9c ON accounts_oidcidentity (user_id);
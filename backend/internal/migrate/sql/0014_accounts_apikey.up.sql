@@ -0,0 +1,20 @@
+-- django: accounts 0005_apikey
+
+CREATE TABLE accounts_apikey (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	name varchar(100) NOT NULL,
+	prefix varchar(16) NOT NULL,
+	key_hash varchar(64) NOT NULL UNIQUE,
+	read_only boolean NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NULL,
+	last_used_at timestamp with time zone NULL,
+	revoked_at timestamp with time zone NULL,
+	project_id bigint NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT accounts_apikey_project_id_9c21854c_fk_projects_project_id FOREIGN KEY (project_id) REFERENCES projects_project (id) DEFERRABLE INITIALLY DEFERRED,
+	CONSTRAINT accounts_apikey_user_id_bd902026_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX accounts_apikey_key_hash_da55e15b_like ON accounts_apikey (key_hash varchar_pattern_ops);
+CREATE INDEX accounts_apikey_project_id_9c21854c ON accounts_apikey (project_id);
+CREATE INDEX accounts_apikey_user_id_bd902026 ON accounts_apikey (user_id);
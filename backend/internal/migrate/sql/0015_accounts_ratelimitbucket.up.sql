@@ -0,0 +1,10 @@
+-- django: accounts 0006_ratelimitbucket
+
+CREATE TABLE accounts_ratelimitbucket (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	key varchar(255) NOT NULL UNIQUE,
+	count integer NOT NULL,
+	window_ends_at timestamp with time zone NOT NULL,
+	blocked_until timestamp with time zone NULL
+);
+CREATE INDEX accounts_ratelimitbucket_key_1ba35032_like ON accounts_ratelimitbucket (key varchar_pattern_ops);
@@ -0,0 +1,5 @@
+DROP TABLE accounts_mfachallenge;
+DROP TABLE accounts_recoverycode;
+DROP TABLE accounts_totpdevice;
+ALTER TABLE accounts_apikey DROP COLUMN mfa_verified;
+ALTER TABLE accounts_session DROP COLUMN mfa_verified;
@@ -0,0 +1,39 @@
+-- django: accounts 0007_two_factor
+
+ALTER TABLE accounts_session ADD COLUMN mfa_verified boolean DEFAULT false NOT NULL;
+ALTER TABLE accounts_session ALTER COLUMN mfa_verified DROP DEFAULT;
+ALTER TABLE accounts_apikey ADD COLUMN mfa_verified boolean DEFAULT false NOT NULL;
+ALTER TABLE accounts_apikey ALTER COLUMN mfa_verified DROP DEFAULT;
+
+CREATE TABLE accounts_totpdevice (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	secret varchar(64) NOT NULL,
+	confirmed_at timestamp with time zone NULL,
+	last_used_step bigint NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	user_id integer NOT NULL UNIQUE,
+	CONSTRAINT accounts_totpdevice_user_id_1f8e3d27_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE TABLE accounts_recoverycode (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	code_hash varchar(64) NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	used_at timestamp with time zone NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT accounts_recoverycode_user_id_994761d3_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX accounts_recoverycode_user_id_994761d3 ON accounts_recoverycode (user_id);
+
+CREATE TABLE accounts_mfachallenge (
+	id bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+	token_hash varchar(64) NOT NULL UNIQUE,
+	attempts integer NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL,
+	used_at timestamp with time zone NULL,
+	user_id integer NOT NULL,
+	CONSTRAINT accounts_mfachallenge_user_id_f2b3cda5_fk_auth_user_id FOREIGN KEY (user_id) REFERENCES auth_user (id) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX accounts_mfachallenge_token_hash_7603cb5f_like ON accounts_mfachallenge (token_hash varchar_pattern_ops);
+CREATE INDEX accounts_mfachallenge_user_id_f2b3cda5 ON accounts_mfachallenge (user_id);
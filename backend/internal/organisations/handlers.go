@@ -0,0 +1,348 @@
+package organisations
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/auth"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Handler serves the organisation API on top of an injected
+// OrganisationRepository.
+type Handler struct {
+	organisations OrganisationRepository
+}
+
+func NewHandler(organisations OrganisationRepository) *Handler {
+	return &Handler{organisations: organisations}
+}
+
+type createOrganisationInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type createOrganisationResponse struct {
+	OrganisationID int64 `json:"organisation_id"`
+}
+
+type addMemberInput struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required,oneof=admin member" example:"member"`
+}
+
+type updateMemberRoleInput struct {
+	UserID int64  `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=admin member" example:"admin"`
+}
+
+type updateSettingsInput struct {
+	Require2FA bool `json:"require_2fa"`
+}
+
+type removeMemberInput struct {
+	UserID int64 `json:"user_id" binding:"required"`
+}
+
+// ListOrganisations godoc
+// @Summary Организации текущего пользователя
+// @Tags organisation
+// @Accept */*
+// @Produce json
+// @Success 200 {array} Organisation "Organisations"
+// @Security BearerAuth
+// @Router /organisation/list [get]
+func (h *Handler) ListOrganisations(c *gin.Context) {
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	organisations, err := h.organisations.GetUserOrganisations(c.Request.Context(), userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisations").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organisations)
+}
+
+// CreateOrganisation godoc
+// @Summary Создание организации
+// @Tags organisation
+// @Accept json
+// @Produce json
+// @Param input body createOrganisationInput true "Organisation information"
+// @Success 200 {object} createOrganisationResponse
+// @Security BearerAuth
+// @Router /organisation/create-organisation [post]
+func (h *Handler) CreateOrganisation(c *gin.Context) {
+	var input createOrganisationInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	organisationID, err := h.organisations.InsertOrganisation(c.Request.Context(), input.Name, userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed create organisation").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, createOrganisationResponse{
+		OrganisationID: organisationID,
+	})
+}
+
+// ListMembers godoc
+// @Summary Участники организации
+// @Tags organisation
+// @Accept */*
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Success 200 {array} Member "Organisation members"
+// @Security BearerAuth
+// @Router /organisation/members [get]
+func (h *Handler) ListMembers(c *gin.Context) {
+	organisationID, err := GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	members, err := h.organisations.GetMembers(c.Request.Context(), organisationID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get members").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// ListProjects godoc
+// @Summary Проекты организации
+// @Description Администраторы видят все проекты организации, остальные участники — только свои.
+// @Tags organisation
+// @Accept */*
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Success 200 {array} Project "Projects"
+// @Security BearerAuth
+// @Router /organisation/projects [get]
+func (h *Handler) ListProjects(c *gin.Context) {
+	organisationID, err := GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	projects, err := h.organisations.GetProjects(c.Request.Context(), organisationID, userID, IsAdmin(c))
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get projects").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// AddMember godoc
+// @Summary Добавление пользователя в организацию
+// @Tags organisation
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body addMemberInput true "Member information"
+// @Success 200 {object} Member "Added member"
+// @Security BearerAuth
+// @Router /organisation/add-member [post]
+func (h *Handler) AddMember(c *gin.Context) {
+	var input addMemberInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	organisationID, err := GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	member, err := h.organisations.InsertMember(c.Request.Context(), organisationID, input.Username, input.Role)
+	if errors.Is(err, ErrUserNotFound) {
+		apperr.Respond(c, apperr.InvalidField("username", "exists", "User not found"))
+		return
+	}
+	if errors.Is(err, ErrAlreadyMember) {
+		apperr.Respond(c, apperr.Conflict("User is already an organisation member"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to add member").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// UpdateMemberRole godoc
+// @Summary Изменение роли участника организации
+// @Tags organisation
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body updateMemberRoleInput true "Member role"
+// @Security BearerAuth
+// @Router /organisation/update-member-role [post]
+func (h *Handler) UpdateMemberRole(c *gin.Context) {
+	var input updateMemberRoleInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	organisationID, err := GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	if input.Role != RoleAdmin && !h.keepsAnAdmin(c, organisationID, input.UserID) {
+		return
+	}
+
+	err = h.organisations.UpdateOrganisationMemberRole(c.Request.Context(), organisationID, input.UserID, input.Role)
+	if errors.Is(err, ErrNotMember) {
+		apperr.Respond(c, apperr.NotFound("Member not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to update member").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// RemoveMember godoc
+// @Summary Удаление участника из организации
+// @Tags organisation
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body removeMemberInput true "Member"
+// @Security BearerAuth
+// @Router /organisation/remove-member [post]
+func (h *Handler) RemoveMember(c *gin.Context) {
+	var input removeMemberInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	organisationID, err := GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	if !h.keepsAnAdmin(c, organisationID, input.UserID) {
+		return
+	}
+
+	err = h.organisations.DeleteMember(c.Request.Context(), organisationID, input.UserID)
+	if errors.Is(err, ErrNotMember) {
+		apperr.Respond(c, apperr.NotFound("Member not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to remove member").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// UpdateSettings godoc
+// @Summary Настройки организации
+// @Description Включить обязательную 2FA может только администратор, сам вошедший с 2FA.
+// @Tags organisation
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body updateSettingsInput true "Organisation settings"
+// @Failure 403 {object} apperr.Response "Two-factor authentication required to enable it"
+// @Security BearerAuth
+// @Router /organisation/update-settings [post]
+func (h *Handler) UpdateSettings(c *gin.Context) {
+	var input updateSettingsInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	organisationID, err := GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	// Otherwise the admin would lock themselves out with the next request.
+	if input.Require2FA && !auth.IsMFAVerified(c) {
+		apperr.Respond(c, apperr.Forbidden("Sign in with two-factor authentication to require it"))
+		return
+	}
+
+	err = h.organisations.UpdateOrganisationRequire2FA(c.Request.Context(), organisationID, input.Require2FA)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to update organisation").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// keepsAnAdmin refuses to demote or remove the last admin of an organisation.
+func (h *Handler) keepsAnAdmin(c *gin.Context, organisationID int64, userID int64) bool {
+	role, err := h.organisations.GetMemberRole(c.Request.Context(), organisationID, userID)
+	if errors.Is(err, ErrNotMember) {
+		apperr.Respond(c, apperr.NotFound("Member not found"))
+		return false
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get member").WithCause(err))
+		return false
+	}
+	if role != RoleAdmin {
+		return true
+	}
+
+	admins, err := h.organisations.CountAdmins(c.Request.Context(), organisationID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get admins").WithCause(err))
+		return false
+	}
+	if admins <= 1 {
+		apperr.Respond(c, apperr.Conflict("Organisation must keep at least one admin"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,384 @@
+package organisations
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	alice = int64(iota + 1)
+	bob
+	carol
+)
+
+// testServer routes the organisation API like cmd/main.go. Requests are made
+// as the user in X-Test-User, with a second factor when X-Test-MFA is set.
+func testServer(organisations OrganisationRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(organisations)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Set("mfa_verified", c.GetHeader("X-Test-MFA") != "")
+	})
+	r.GET("/organisation/list", h.ListOrganisations)
+	r.POST("/organisation/create-organisation", h.CreateOrganisation)
+
+	tenant := r.Group("/organisation")
+	tenant.Use(TenantMiddleware(organisations))
+	tenant.GET("/members", h.ListMembers)
+	tenant.GET("/projects", h.ListProjects)
+
+	tenantAdmin := tenant.Group("")
+	tenantAdmin.Use(AdminMiddleware())
+	tenantAdmin.POST("/add-member", h.AddMember)
+	tenantAdmin.POST("/update-member-role", h.UpdateMemberRole)
+	tenantAdmin.POST("/remove-member", h.RemoveMember)
+	tenantAdmin.POST("/update-settings", h.UpdateSettings)
+	return r
+}
+
+type testRequest struct {
+	method         string
+	path           string
+	userID         int64
+	organisationID int64
+	mfa            bool
+	body           string
+}
+
+func serve(r *gin.Engine, tr testRequest) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(tr.method, tr.path, strings.NewReader(tr.body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", strconv.FormatInt(tr.userID, 10))
+	if tr.organisationID != 0 {
+		req.Header.Set("X-Organisation-ID", strconv.FormatInt(tr.organisationID, 10))
+	}
+	if tr.mfa {
+		req.Header.Set("X-Test-MFA", "1")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// seed creates an organisation with alice as admin and bob as member, and
+// carol, who is in no organisation.
+func seed(t *testing.T) (*MemoryOrganisationRepository, int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	organisations := NewMemoryOrganisationRepository()
+	organisations.AddUser(alice, "alice")
+	organisations.AddUser(bob, "bob")
+	organisations.AddUser(carol, "carol")
+
+	organisationID, err := organisations.InsertOrganisation(ctx, "Acme", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := organisations.InsertMember(ctx, organisationID, "bob", RoleMember); err != nil {
+		t.Fatal(err)
+	}
+	return organisations, organisationID
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body, err)
+	}
+	return v
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	organisations, organisationID := seed(t)
+	otherID, err := organisations.InsertOrganisation(context.Background(), "Other", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	required, err := organisations.InsertOrganisation(context.Background(), "Strict", bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := organisations.UpdateOrganisationRequire2FA(context.Background(), required, true); err != nil {
+		t.Fatal(err)
+	}
+	r := testServer(organisations)
+
+	tests := []struct {
+		name           string
+		userID         int64
+		organisationID int64
+		header         string
+		mfa            bool
+		wantStatus     int
+	}{
+		{"several organisations without header", alice, 0, "", false, http.StatusBadRequest},
+		{"no organisation", carol, 0, "", false, http.StatusBadRequest},
+		{"member", alice, otherID, "", false, http.StatusOK},
+		{"not a member", carol, organisationID, "", false, http.StatusForbidden},
+		{"unknown organisation", alice, 999, "", false, http.StatusForbidden},
+		{"invalid header", alice, 0, "acme", false, http.StatusBadRequest},
+		{"2FA required, not verified", bob, required, "", false, http.StatusForbidden},
+		{"2FA required and verified", bob, required, "", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/organisation/members", nil)
+			req.Header.Set("X-Test-User", strconv.FormatInt(tt.userID, 10))
+			if tt.organisationID != 0 {
+				req.Header.Set("X-Organisation-ID", strconv.FormatInt(tt.organisationID, 10))
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Organisation-ID", tt.header)
+			}
+			if tt.mfa {
+				req.Header.Set("X-Test-MFA", "1")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestTenantMiddlewareResolvesSingleOrganisation(t *testing.T) {
+	organisations := NewMemoryOrganisationRepository()
+	organisations.AddUser(alice, "alice")
+	organisationID, err := organisations.InsertOrganisation(context.Background(), "Acme", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := testServer(organisations)
+
+	w := serve(r, testRequest{method: http.MethodGet, path: "/organisation/members", userID: alice})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	members := decode[[]Member](t, w)
+	if len(members) != 1 || members[0].UserID != alice {
+		t.Errorf("members of %d = %+v, want alice", organisationID, members)
+	}
+}
+
+func TestListAndCreateOrganisations(t *testing.T) {
+	organisations, _ := seed(t)
+	r := testServer(organisations)
+
+	w := serve(r, testRequest{method: http.MethodPost, path: "/organisation/create-organisation", userID: carol, body: `{"name": "Carol's"}`})
+	if w.Code != http.StatusOK {
+		t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+	}
+	created := decode[createOrganisationResponse](t, w)
+
+	w = serve(r, testRequest{method: http.MethodGet, path: "/organisation/list", userID: carol})
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: status = %d: %s", w.Code, w.Body)
+	}
+	want := []Organisation{{ID: created.OrganisationID, Name: "Carol's", Role: RoleAdmin}}
+	if got := decode[[]Organisation](t, w); !slices.Equal(got, want) {
+		t.Errorf("organisations = %+v, want %+v", got, want)
+	}
+
+	w = serve(r, testRequest{method: http.MethodPost, path: "/organisation/create-organisation", userID: carol, body: `{}`})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create without name: status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestListProjects(t *testing.T) {
+	organisations, organisationID := seed(t)
+	shared := organisations.AddProject(organisationID, "Shared", alice, bob)
+	private := organisations.AddProject(organisationID, "Private", alice)
+	organisations.AddProject(999, "Elsewhere", alice, bob)
+	r := testServer(organisations)
+
+	tests := []struct {
+		name   string
+		userID int64
+		want   []Project
+	}{
+		{"admin sees all", alice, []Project{{ID: private, Name: "Private"}, {ID: shared, Name: "Shared"}}},
+		{"member sees own", bob, []Project{{ID: shared, Name: "Shared"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, testRequest{method: http.MethodGet, path: "/organisation/projects", userID: tt.userID, organisationID: organisationID})
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if got := decode[[]Project](t, w); !slices.Equal(got, tt.want) {
+				t.Errorf("projects = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminEndpointsRequireAdmin(t *testing.T) {
+	organisations, organisationID := seed(t)
+	r := testServer(organisations)
+
+	for _, path := range []string{"/organisation/add-member", "/organisation/update-member-role", "/organisation/remove-member", "/organisation/update-settings"} {
+		w := serve(r, testRequest{method: http.MethodPost, path: path, userID: bob, organisationID: organisationID, body: `{}`})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s as member: status = %d, want %d", path, w.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestAddMember(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"new member", `{"username": "carol", "role": "member"}`, http.StatusOK},
+		{"unknown user", `{"username": "dave", "role": "member"}`, http.StatusUnprocessableEntity},
+		{"already a member", `{"username": "bob", "role": "admin"}`, http.StatusConflict},
+		{"invalid role", `{"username": "carol", "role": "owner"}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			organisations, organisationID := seed(t)
+			r := testServer(organisations)
+
+			w := serve(r, testRequest{method: http.MethodPost, path: "/organisation/add-member", userID: alice, organisationID: organisationID, body: tt.body})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			want := Member{UserID: carol, Username: "carol", Role: RoleMember}
+			if got := decode[Member](t, w); got != want {
+				t.Errorf("member = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantRoles  map[int64]string
+	}{
+		{"promote", `{"user_id": 2, "role": "admin"}`, http.StatusOK, map[int64]string{alice: RoleAdmin, bob: RoleAdmin}},
+		{"demote last admin", `{"user_id": 1, "role": "member"}`, http.StatusConflict, map[int64]string{alice: RoleAdmin, bob: RoleMember}},
+		{"not a member", `{"user_id": 3, "role": "admin"}`, http.StatusNotFound, map[int64]string{alice: RoleAdmin, bob: RoleMember}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			organisations, organisationID := seed(t)
+			r := testServer(organisations)
+
+			w := serve(r, testRequest{method: http.MethodPost, path: "/organisation/update-member-role", userID: alice, organisationID: organisationID, body: tt.body})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			for userID, want := range tt.wantRoles {
+				if role, _ := organisations.GetMemberRole(context.Background(), organisationID, userID); role != want {
+					t.Errorf("role of %d = %q, want %q", userID, role, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	organisations, organisationID := seed(t)
+	organisations.AddProject(organisationID, "Site", alice, bob)
+	otherOrganisationID, err := organisations.InsertOrganisation(context.Background(), "Other", bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherProjectID := organisations.AddProject(otherOrganisationID, "Elsewhere", bob)
+	r := testServer(organisations)
+
+	remove := func(userID int64) *httptest.ResponseRecorder {
+		body := `{"user_id": ` + strconv.FormatInt(userID, 10) + `}`
+		return serve(r, testRequest{method: http.MethodPost, path: "/organisation/remove-member", userID: alice, organisationID: organisationID, body: body})
+	}
+
+	if w := remove(alice); w.Code != http.StatusConflict {
+		t.Errorf("remove last admin: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := remove(carol); w.Code != http.StatusNotFound {
+		t.Errorf("remove non-member: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := remove(bob); w.Code != http.StatusOK {
+		t.Fatalf("remove member: status = %d: %s", w.Code, w.Body)
+	}
+
+	ctx := context.Background()
+	if _, err := organisations.GetMemberRole(ctx, organisationID, bob); err != ErrNotMember {
+		t.Errorf("bob is still a member: %v", err)
+	}
+	projects, err := organisations.GetProjects(ctx, organisationID, bob, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 0 {
+		t.Errorf("bob still sees %+v", projects)
+	}
+	projects, err = organisations.GetProjects(ctx, otherOrganisationID, bob, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Project{{ID: otherProjectID, Name: "Elsewhere"}}; !slices.Equal(projects, want) {
+		t.Errorf("projects of bob's other organisation = %+v, want %+v", projects, want)
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		mfa          bool
+		wantStatus   int
+		wantRequired bool
+	}{
+		{"require without 2FA", `{"require_2fa": true}`, false, http.StatusForbidden, false},
+		{"require with 2FA", `{"require_2fa": true}`, true, http.StatusOK, true},
+		{"drop requirement", `{"require_2fa": false}`, false, http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			organisations, organisationID := seed(t)
+			r := testServer(organisations)
+
+			w := serve(r, testRequest{method: http.MethodPost, path: "/organisation/update-settings", userID: alice, organisationID: organisationID, mfa: tt.mfa, body: tt.body})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			required, err := organisations.RequiresTwoFactor(context.Background(), organisationID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if required != tt.wantRequired {
+				t.Errorf("require_2fa = %v, want %v", required, tt.wantRequired)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package organisations
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+)
+
+type memoryOrganisation struct {
+	ID         int64
+	Name       string
+	Require2FA bool
+	Members    map[int64]string
+}
+
+type memoryProject struct {
+	ID             int64
+	OrganisationID int64
+	Name           string
+	Members        map[int64]bool
+}
+
+// MemoryOrganisationRepository is an OrganisationRepository that keeps
+// everything in maps behind a mutex. It follows the rules of
+// PostgresOrganisationRepository and is meant for tests. Users and projects
+// live in the auth and projects tables, so tests seed them with AddUser and
+// AddProject.
+type MemoryOrganisationRepository struct {
+	mu            sync.Mutex
+	nextID        int64
+	usernames     map[int64]string
+	organisations map[int64]*memoryOrganisation
+	projects      map[int64]*memoryProject
+}
+
+func NewMemoryOrganisationRepository() *MemoryOrganisationRepository {
+	return &MemoryOrganisationRepository{
+		usernames:     map[int64]string{},
+		organisations: map[int64]*memoryOrganisation{},
+		projects:      map[int64]*memoryProject{},
+	}
+}
+
+func (r *MemoryOrganisationRepository) AddUser(userID int64, username string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.usernames[userID] = username
+}
+
+// AddProject creates a project of the organisation with the given users as
+// project members and returns its ID.
+func (r *MemoryOrganisationRepository) AddProject(organisationID int64, name string, memberIDs ...int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project := &memoryProject{ID: r.newID(), OrganisationID: organisationID, Name: name, Members: map[int64]bool{}}
+	for _, userID := range memberIDs {
+		project.Members[userID] = true
+	}
+	r.projects[project.ID] = project
+	return project.ID
+}
+
+func (r *MemoryOrganisationRepository) newID() int64 {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *MemoryOrganisationRepository) GetUserOrganisations(ctx context.Context, userID int64) ([]Organisation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	organisations := []Organisation{}
+	for _, o := range r.organisations {
+		if role, ok := o.Members[userID]; ok {
+			organisations = append(organisations, Organisation{ID: o.ID, Name: o.Name, Role: role, Require2FA: o.Require2FA})
+		}
+	}
+	sort.Slice(organisations, func(i, j int) bool {
+		if organisations[i].Name != organisations[j].Name {
+			return organisations[i].Name < organisations[j].Name
+		}
+		return organisations[i].ID < organisations[j].ID
+	})
+	return organisations, nil
+}
+
+func (r *MemoryOrganisationRepository) InsertOrganisation(ctx context.Context, name string, userID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.newID()
+	r.organisations[id] = &memoryOrganisation{ID: id, Name: name, Members: map[int64]string{userID: RoleAdmin}}
+	return id, nil
+}
+
+func (r *MemoryOrganisationRepository) RequiresTwoFactor(ctx context.Context, organisationID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.organisations[organisationID]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return o.Require2FA, nil
+}
+
+func (r *MemoryOrganisationRepository) UpdateOrganisationRequire2FA(ctx context.Context, organisationID int64, required bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o, ok := r.organisations[organisationID]; ok {
+		o.Require2FA = required
+	}
+	return nil
+}
+
+func (r *MemoryOrganisationRepository) GetMemberRole(ctx context.Context, organisationID int64, userID int64) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.organisations[organisationID]
+	if !ok {
+		return "", ErrNotMember
+	}
+	role, ok := o.Members[userID]
+	if !ok {
+		return "", ErrNotMember
+	}
+	return role, nil
+}
+
+func (r *MemoryOrganisationRepository) GetMembers(ctx context.Context, organisationID int64) ([]Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := []Member{}
+	if o, ok := r.organisations[organisationID]; ok {
+		for userID, role := range o.Members {
+			members = append(members, Member{UserID: userID, Username: r.usernames[userID], Role: role})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Username < members[j].Username })
+	return members, nil
+}
+
+func (r *MemoryOrganisationRepository) InsertMember(ctx context.Context, organisationID int64, username string, role string) (Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for userID, name := range r.usernames {
+		if name != username {
+			continue
+		}
+		o, ok := r.organisations[organisationID]
+		if !ok {
+			return Member{}, ErrUserNotFound
+		}
+		if _, ok := o.Members[userID]; ok {
+			return Member{}, ErrAlreadyMember
+		}
+		o.Members[userID] = role
+		return Member{UserID: userID, Username: username, Role: role}, nil
+	}
+	return Member{}, ErrUserNotFound
+}
+
+func (r *MemoryOrganisationRepository) CountAdmins(ctx context.Context, organisationID int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	if o, ok := r.organisations[organisationID]; ok {
+		for _, role := range o.Members {
+			if role == RoleAdmin {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryOrganisationRepository) UpdateOrganisationMemberRole(ctx context.Context, organisationID int64, userID int64, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.organisations[organisationID]
+	if !ok {
+		return ErrNotMember
+	}
+	if _, ok := o.Members[userID]; !ok {
+		return ErrNotMember
+	}
+	o.Members[userID] = role
+	return nil
+}
+
+func (r *MemoryOrganisationRepository) DeleteMember(ctx context.Context, organisationID int64, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.organisations[organisationID]
+	if !ok {
+		return ErrNotMember
+	}
+	if _, ok := o.Members[userID]; !ok {
+		return ErrNotMember
+	}
+	delete(o.Members, userID)
+	for _, project := range r.projects {
+		if project.OrganisationID == organisationID {
+			delete(project.Members, userID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryOrganisationRepository) GetProjects(ctx context.Context, organisationID int64, userID int64, all bool) ([]Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	projects := []Project{}
+	for _, project := range r.projects {
+		if project.OrganisationID == organisationID && (all || project.Members[userID]) {
+			projects = append(projects, Project{ID: project.ID, Name: project.Name})
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
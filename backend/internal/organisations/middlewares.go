@@ -0,0 +1,96 @@
+package organisations
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/auth"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+// TenantMiddleware resolves the organisation the request acts on from the
+// X-Organisation-ID header. Users with a single organisation may omit it.
+// Organisations that require 2FA only admit sessions that passed it.
+func TenantMiddleware(organisations OrganisationRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := auth.GetUserID(c)
+		if err != nil {
+			apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+			return
+		}
+
+		var organisationID int64
+		if header := c.GetHeader("X-Organisation-ID"); header != "" {
+			organisationID, err = strconv.ParseInt(header, 10, 64)
+			if err != nil {
+				apperr.Respond(c, apperr.BadRequest("Invalid X-Organisation-ID header"))
+				return
+			}
+		} else {
+			memberships, err := organisations.GetUserOrganisations(c.Request.Context(), userID)
+			if err != nil {
+				apperr.Respond(c, apperr.Internal("Failed to get organisations").WithCause(err))
+				return
+			}
+			if len(memberships) != 1 {
+				apperr.Respond(c, apperr.BadRequest("X-Organisation-ID header is required"))
+				return
+			}
+			organisationID = memberships[0].ID
+		}
+
+		role, err := organisations.GetMemberRole(c.Request.Context(), organisationID, userID)
+		if errors.Is(err, ErrNotMember) {
+			apperr.Respond(c, apperr.Forbidden("Access to organisation denied"))
+			return
+		}
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to get organisation role").WithCause(err))
+			return
+		}
+
+		required, err := organisations.RequiresTwoFactor(c.Request.Context(), organisationID)
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+			return
+		}
+		if required && !auth.IsMFAVerified(c) {
+			apperr.Respond(c, apperr.Forbidden("Organisation requires two-factor authentication"))
+			return
+		}
+
+		c.Set("organisation_id", organisationID)
+		c.Set("organisation_role", role)
+		c.Next()
+	}
+}
+
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(c) {
+			apperr.Respond(c, apperr.Forbidden("Organisation admin privileges required"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func GetOrganisationID(c *gin.Context) (int64, error) {
+	value, exists := c.Get("organisation_id")
+	if !exists {
+		return 0, errors.New("organisation is not resolved")
+	}
+
+	organisationID, ok := value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected organisation id type: %T", value)
+	}
+
+	return organisationID, nil
+}
+
+func IsAdmin(c *gin.Context) bool {
+	return c.GetString("organisation_role") == RoleAdmin
+}
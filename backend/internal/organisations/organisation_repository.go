@@ -0,0 +1,38 @@
+package organisations
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// OrganisationRepository is the storage behind the organisation handlers and
+// TenantMiddleware: organisations, their members and settings.
+// PostgresOrganisationRepository works on the tables of the Django admin,
+// MemoryOrganisationRepository keeps everything in memory for tests.
+type OrganisationRepository interface {
+	GetUserOrganisations(ctx context.Context, userID int64) ([]Organisation, error)
+	InsertOrganisation(ctx context.Context, name string, userID int64) (int64, error)
+	RequiresTwoFactor(ctx context.Context, organisationID int64) (bool, error)
+	UpdateOrganisationRequire2FA(ctx context.Context, organisationID int64, required bool) error
+
+	GetMemberRole(ctx context.Context, organisationID int64, userID int64) (string, error)
+	GetMembers(ctx context.Context, organisationID int64) ([]Member, error)
+	InsertMember(ctx context.Context, organisationID int64, username string, role string) (Member, error)
+	CountAdmins(ctx context.Context, organisationID int64) (int, error)
+	UpdateOrganisationMemberRole(ctx context.Context, organisationID int64, userID int64, role string) error
+	DeleteMember(ctx context.Context, organisationID int64, userID int64) error
+
+	GetProjects(ctx context.Context, organisationID int64, userID int64, all bool) ([]Project, error)
+}
+
+type PostgresOrganisationRepository struct {
+	db           *sqlx.DB
+	queryTimeout time.Duration
+}
+
+// NewPostgresOrganisationRepository returns a repository whose every method
+// gives up after queryTimeout, or earlier when its context ends.
+func NewPostgresOrganisationRepository(db *sqlx.DB, queryTimeout time.Duration) *PostgresOrganisationRepository {
+	return &PostgresOrganisationRepository{db: db, queryTimeout: queryTimeout}
+}
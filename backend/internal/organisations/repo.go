@@ -0,0 +1,275 @@
+package organisations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var (
+	ErrNotMember     = errors.New("user is not an organisation member")
+	ErrAlreadyMember = errors.New("user is already an organisation member")
+	ErrUserNotFound  = errors.New("user not found")
+)
+
+type Organisation struct {
+	ID         int64  `db:"id" json:"id"`
+	Name       string `db:"name" json:"name"`
+	Role       string `db:"role" json:"role"`
+	Require2FA bool   `db:"require_2fa" json:"require_2fa"`
+}
+
+type Member struct {
+	UserID   int64  `db:"user_id" json:"user_id"`
+	Username string `db:"username" json:"username"`
+	Role     string `db:"role" json:"role"`
+}
+
+type Project struct {
+	ID   int64  `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+}
+
+func (r *PostgresOrganisationRepository) GetUserOrganisations(ctx context.Context, userID int64) ([]Organisation, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	organisations := []Organisation{}
+	query := `
+		SELECT o.id, o.name, om.role, o.require_2fa
+		FROM projects_organisation o
+		JOIN projects_organisationmember om ON om.organisation_id = o.id
+		WHERE om.user_id = $1
+		ORDER BY o.name;
+	`
+	err := r.db.SelectContext(ctx, &organisations, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return organisations, nil
+}
+
+func (r *PostgresOrganisationRepository) GetMemberRole(ctx context.Context, organisationID int64, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var role string
+	query := `SELECT role FROM projects_organisationmember WHERE organisation_id = $1 AND user_id = $2`
+	err := r.db.GetContext(ctx, &role, query, organisationID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *PostgresOrganisationRepository) RequiresTwoFactor(ctx context.Context, organisationID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var required bool
+	err := r.db.GetContext(ctx, &required, `SELECT require_2fa FROM projects_organisation WHERE id = $1`, organisationID)
+	if err != nil {
+		return false, err
+	}
+	return required, nil
+}
+
+func (r *PostgresOrganisationRepository) UpdateOrganisationRequire2FA(ctx context.Context, organisationID int64, required bool) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE projects_organisation SET require_2fa = $1 WHERE id = $2`, required, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to update organisation: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresOrganisationRepository) InsertOrganisation(ctx context.Context, name string, userID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	var organisationID int64
+	query := `
+		INSERT INTO projects_organisation (name, created_at, require_2fa)
+		VALUES ($1, now(), false)
+		RETURNING id;
+	`
+	err = tx.GetContext(ctx, &organisationID, query, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create organisation: %w", err)
+	}
+
+	memberQuery := `
+		INSERT INTO projects_organisationmember (organisation_id, user_id, role)
+		VALUES ($1, $2, $3);
+	`
+	_, err = tx.ExecContext(ctx, memberQuery, organisationID, userID, RoleAdmin)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add organisation admin: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return organisationID, nil
+}
+
+func (r *PostgresOrganisationRepository) GetMembers(ctx context.Context, organisationID int64) ([]Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	members := []Member{}
+	query := `
+		SELECT om.user_id, u.username, om.role
+		FROM projects_organisationmember om
+		JOIN auth_user u ON u.id = om.user_id
+		WHERE om.organisation_id = $1
+		ORDER BY u.username;
+	`
+	err := r.db.SelectContext(ctx, &members, query, organisationID)
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *PostgresOrganisationRepository) InsertMember(ctx context.Context, organisationID int64, username string, role string) (Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO projects_organisationmember (organisation_id, user_id, role)
+		SELECT $1, u.id, $3
+		FROM auth_user u
+		WHERE u.username = $2
+		RETURNING user_id, $2::varchar AS username, role;
+	`
+	var member Member
+	err := r.db.GetContext(ctx, &member, query, organisationID, username, role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return member, ErrUserNotFound
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return member, ErrAlreadyMember
+		}
+		return member, fmt.Errorf("failed to add organisation member: %w", err)
+	}
+	return member, nil
+}
+
+func (r *PostgresOrganisationRepository) CountAdmins(ctx context.Context, organisationID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var count int
+	query := `SELECT count(*) FROM projects_organisationmember WHERE organisation_id = $1 AND role = $2`
+	err := r.db.GetContext(ctx, &count, query, organisationID, RoleAdmin)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PostgresOrganisationRepository) UpdateOrganisationMemberRole(ctx context.Context, organisationID int64, userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE projects_organisationmember
+		SET role = $1
+		WHERE organisation_id = $2 AND user_id = $3;
+	`
+	res, err := r.db.ExecContext(ctx, query, role, organisationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update organisation member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// DeleteMember removes the user from the organisation together with their
+// memberships in the organisation's projects.
+func (r *PostgresOrganisationRepository) DeleteMember(ctx context.Context, organisationID int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	projectsQuery := `
+		DELETE FROM projects_project_user pu
+		USING projects_project p
+		WHERE pu.project_id = p.id AND p.organisation_id = $1 AND pu.user_id = $2;
+	`
+	_, err = tx.ExecContext(ctx, projectsQuery, organisationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove project memberships: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM projects_organisationmember WHERE organisation_id = $1 AND user_id = $2`, organisationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove organisation member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotMember
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetProjects lists every project of the organisation when all is set and
+// only the projects userID is a member of otherwise.
+func (r *PostgresOrganisationRepository) GetProjects(ctx context.Context, organisationID int64, userID int64, all bool) ([]Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	projects := []Project{}
+	query := `
+		SELECT p.id, p.name
+		FROM projects_project p
+		WHERE p.organisation_id = $1
+			AND ($3 OR EXISTS (
+				SELECT 1 FROM projects_project_user pu
+				WHERE pu.project_id = p.id AND pu.user_id = $2
+			))
+		ORDER BY p.name, p.id;
+	`
+	err := r.db.SelectContext(ctx, &projects, query, organisationID, userID, all)
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
@@ -0,0 +1,310 @@
+package projects
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/organisations"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Comment struct {
+	ID             int64       `json:"id"`
+	ProjectID      int64       `json:"project_id"`
+	BuildingID     *int64      `json:"building_id,omitempty"`
+	ParentID       *int64      `json:"parent_id,omitempty"`
+	AuthorID       int64       `json:"author_id"`
+	AuthorUsername string      `json:"author_username"`
+	Text           string      `json:"text"`
+	Point          *Coordinate `json:"point,omitempty"`
+	Resolved       bool        `json:"resolved"`
+	ResolvedByID   *int64      `json:"resolved_by_id,omitempty"`
+	ResolvedAt     *time.Time  `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Mentions       []string    `json:"mentions"`
+	Replies        []Comment   `json:"replies"`
+}
+
+type createCommentInput struct {
+	ProjectID  int64       `json:"project_id" binding:"required"`
+	BuildingID *int64      `json:"building_id"`
+	ParentID   *int64      `json:"parent_id"`
+	Text       string      `json:"text" binding:"required" example:"Уменьшить до 9 этажей @ivan"`
+	Point      *Coordinate `json:"point"`
+}
+
+type createCommentResponse struct {
+	CommentID int64 `json:"comment_id"`
+}
+
+type commentStateInput struct {
+	CommentID int64 `json:"comment_id" binding:"required"`
+}
+
+var mentionRegexp = regexp.MustCompile(`(?:^|\s)@([\w.@+-]+)`)
+
+func parseMentions(text string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	for _, match := range mentionRegexp.FindAllStringSubmatch(text, -1) {
+		username := strings.TrimRight(match[1], ".")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+func commentFromRow(row CommentRow) Comment {
+	comment := Comment{
+		ID:             row.ID,
+		ProjectID:      row.ProjectID,
+		AuthorID:       row.AuthorID,
+		AuthorUsername: row.AuthorUsername,
+		Text:           row.Text,
+		Resolved:       row.IsResolved,
+		CreatedAt:      row.CreatedAt,
+		Mentions:       row.Mentions,
+		Replies:        []Comment{},
+	}
+	if row.BuildingID.Valid {
+		comment.BuildingID = &row.BuildingID.Int64
+	}
+	if row.ParentID.Valid {
+		comment.ParentID = &row.ParentID.Int64
+	}
+	if row.PointX.Valid && row.PointY.Valid {
+		comment.Point = &Coordinate{X: row.PointX.Float64, Y: row.PointY.Float64}
+	}
+	if row.ResolvedByID.Valid {
+		comment.ResolvedByID = &row.ResolvedByID.Int64
+	}
+	if row.ResolvedAt.Valid {
+		comment.ResolvedAt = &row.ResolvedAt.Time
+	}
+	return comment
+}
+
+// buildCommentThreads nests replies under their parents. Rows must be ordered
+// by creation time so that a parent always precedes its replies.
+func buildCommentThreads(rows []CommentRow) []Comment {
+	children := map[int64][]int64{}
+	comments := map[int64]Comment{}
+	var roots []int64
+
+	for _, row := range rows {
+		comments[row.ID] = commentFromRow(row)
+		if row.ParentID.Valid {
+			children[row.ParentID.Int64] = append(children[row.ParentID.Int64], row.ID)
+		} else {
+			roots = append(roots, row.ID)
+		}
+	}
+
+	var build func(id int64) Comment
+	build = func(id int64) Comment {
+		comment := comments[id]
+		for _, childID := range children[id] {
+			comment.Replies = append(comment.Replies, build(childID))
+		}
+		return comment
+	}
+
+	threads := []Comment{}
+	for _, id := range roots {
+		threads = append(threads, build(id))
+	}
+	return threads
+}
+
+// ListComments godoc
+// @Summary Список комментариев проекта или здания
+// @Tags comments
+// @Accept */*
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param project_id query int true "Project ID"
+// @Param building_id query int false "Building ID"
+// @Success 200 {array} Comment "Comment threads"
+// @Security BearerAuth
+// @Router /project/comments [get]
+func (h *Handler) ListComments(c *gin.Context) {
+	projectID, err := strconv.ParseInt(c.Query("project_id"), 10, 64)
+	if err != nil {
+		apperr.Respond(c, apperr.InvalidField("project_id", "integer", "Invalid project_id"))
+		return
+	}
+
+	var buildingID *int64
+	if param := c.Query("building_id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			apperr.Respond(c, apperr.InvalidField("building_id", "integer", "Invalid building_id"))
+			return
+		}
+		buildingID = &id
+	}
+
+	if _, ok := h.authorizeProject(c, projectID, RoleViewer); !ok {
+		return
+	}
+
+	rows, err := h.projects.GetCommentRows(c.Request.Context(), projectID, buildingID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get comments").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, buildCommentThreads(rows))
+}
+
+// CreateComment godoc
+// @Summary Создание комментария
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body createCommentInput true "Comment information"
+// @Success 200 {object} createCommentResponse
+// @Security BearerAuth
+// @Router /project/create-comment [post]
+func (h *Handler) CreateComment(c *gin.Context) {
+	var input createCommentInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, input.ProjectID, RoleViewer)
+	if !ok {
+		return
+	}
+
+	comment := NewComment{
+		ProjectID:  input.ProjectID,
+		BuildingID: input.BuildingID,
+		ParentID:   input.ParentID,
+		AuthorID:   access.UserID,
+		Text:       input.Text,
+		Point:      input.Point,
+	}
+
+	if input.ParentID != nil {
+		parent, err := h.projects.GetCommentRow(c.Request.Context(), access.OrganisationID, *input.ParentID)
+		if errors.Is(err, ErrCommentNotFound) || (err == nil && parent.ProjectID != input.ProjectID) {
+			apperr.Respond(c, apperr.InvalidField("parent_id", "exists", "Parent comment not found in the project"))
+			return
+		}
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to get parent comment").WithCause(err))
+			return
+		}
+		// Replies belong to the parent's thread, so they share its anchor.
+		comment.BuildingID = nil
+		if parent.BuildingID.Valid {
+			comment.BuildingID = &parent.BuildingID.Int64
+		}
+		comment.Point = nil
+	} else if input.BuildingID != nil {
+		projectID, err := h.projects.GetObjectProjectID(c.Request.Context(), access.OrganisationID, LockObjectBuilding, *input.BuildingID)
+		if errors.Is(err, ErrProjectNotFound) || (err == nil && projectID != input.ProjectID) {
+			apperr.Respond(c, apperr.InvalidField("building_id", "exists", "Building not found in the project"))
+			return
+		}
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to get building").WithCause(err))
+			return
+		}
+	}
+
+	members, err := h.projects.GetProjectMembersByUsernames(c.Request.Context(), input.ProjectID, parseMentions(input.Text))
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to resolve mentions").WithCause(err))
+		return
+	}
+	mentionIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		mentionIDs = append(mentionIDs, member.ID)
+	}
+
+	commentID, err := h.projects.InsertComment(c.Request.Context(), comment, mentionIDs)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed create comment").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, createCommentResponse{
+		CommentID: commentID,
+	})
+}
+
+// ResolveComment godoc
+// @Summary Пометить комментарий решённым
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body commentStateInput true "Comment"
+// @Security BearerAuth
+// @Router /project/resolve-comment [post]
+func (h *Handler) ResolveComment(c *gin.Context) {
+	h.setCommentResolved(c, true)
+}
+
+// ReopenComment godoc
+// @Summary Переоткрыть комментарий
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body commentStateInput true "Comment"
+// @Security BearerAuth
+// @Router /project/reopen-comment [post]
+func (h *Handler) ReopenComment(c *gin.Context) {
+	h.setCommentResolved(c, false)
+}
+
+func (h *Handler) setCommentResolved(c *gin.Context, resolved bool) {
+	var input commentStateInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	organisationID, err := organisations.GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	comment, err := h.projects.GetCommentRow(c.Request.Context(), organisationID, input.CommentID)
+	if errors.Is(err, ErrCommentNotFound) {
+		apperr.Respond(c, apperr.NotFound("Comment not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get comment").WithCause(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, comment.ProjectID, RoleViewer)
+	if !ok {
+		return
+	}
+
+	err = h.projects.SetCommentResolved(c.Request.Context(), access.OrganisationID, input.CommentID, resolved, access.UserID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed update comment").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
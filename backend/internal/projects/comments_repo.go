@@ -0,0 +1,181 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+type CommentRow struct {
+	ID             int64           `db:"id"`
+	ProjectID      int64           `db:"project_id"`
+	BuildingID     sql.NullInt64   `db:"building_id"`
+	ParentID       sql.NullInt64   `db:"parent_id"`
+	AuthorID       int64           `db:"author_id"`
+	AuthorUsername string          `db:"author_username"`
+	Text           string          `db:"text"`
+	PointX         sql.NullFloat64 `db:"point_x"`
+	PointY         sql.NullFloat64 `db:"point_y"`
+	IsResolved     bool            `db:"is_resolved"`
+	ResolvedByID   sql.NullInt64   `db:"resolved_by_id"`
+	ResolvedAt     sql.NullTime    `db:"resolved_at"`
+	CreatedAt      time.Time       `db:"created_at"`
+	Mentions       pq.StringArray  `db:"mentions"`
+}
+
+type NewComment struct {
+	ProjectID  int64
+	BuildingID *int64
+	ParentID   *int64
+	AuthorID   int64
+	Text       string
+	Point      *Coordinate
+}
+
+type ProjectMember struct {
+	ID       int64  `db:"id"`
+	Username string `db:"username"`
+}
+
+func (r *PostgresProjectRepository) GetCommentRow(ctx context.Context, organisationID int64, commentID int64) (CommentRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var row CommentRow
+	query := `
+		SELECT
+			c.id, c.project_id, c.building_id, c.parent_id, c.author_id, a.username AS author_username,
+			c.text, c.point_x, c.point_y, c.is_resolved, c.resolved_by_id, c.resolved_at, c.created_at,
+			COALESCE(array_agg(m.username ORDER BY m.username) FILTER (WHERE m.username IS NOT NULL), '{}') AS mentions
+		FROM projects_comment c
+		JOIN projects_project pr ON pr.id = c.project_id
+		JOIN auth_user a ON a.id = c.author_id
+		LEFT JOIN projects_comment_mentions cm ON cm.comment_id = c.id
+		LEFT JOIN auth_user m ON m.id = cm.user_id
+		WHERE c.id = $1 AND pr.organisation_id = $2
+		GROUP BY c.id, a.username;
+	`
+	err := r.db.GetContext(ctx, &row, query, commentID, organisationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return row, ErrCommentNotFound
+		}
+		return row, err
+	}
+	return row, nil
+}
+
+// GetCommentRows returns all comments of a project in creation order. When
+// buildingID is set only the threads attached to that building are returned.
+func (r *PostgresProjectRepository) GetCommentRows(ctx context.Context, projectID int64, buildingID *int64) ([]CommentRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows := []CommentRow{}
+	query := `
+		SELECT
+			c.id, c.project_id, c.building_id, c.parent_id, c.author_id, a.username AS author_username,
+			c.text, c.point_x, c.point_y, c.is_resolved, c.resolved_by_id, c.resolved_at, c.created_at,
+			COALESCE(array_agg(m.username ORDER BY m.username) FILTER (WHERE m.username IS NOT NULL), '{}') AS mentions
+		FROM projects_comment c
+		JOIN auth_user a ON a.id = c.author_id
+		LEFT JOIN projects_comment_mentions cm ON cm.comment_id = c.id
+		LEFT JOIN auth_user m ON m.id = cm.user_id
+		WHERE c.project_id = $1 AND ($2::bigint IS NULL OR c.building_id = $2)
+		GROUP BY c.id, a.username
+		ORDER BY c.created_at, c.id;
+	`
+	err := r.db.SelectContext(ctx, &rows, query, projectID, buildingID)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *PostgresProjectRepository) GetProjectMembersByUsernames(ctx context.Context, projectID int64, usernames []string) ([]ProjectMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	members := []ProjectMember{}
+	if len(usernames) == 0 {
+		return members, nil
+	}
+
+	query := `
+		SELECT u.id, u.username
+		FROM auth_user u
+		JOIN projects_project_user pu ON pu.user_id = u.id
+		WHERE pu.project_id = $1 AND u.username = ANY($2);
+	`
+	err := r.db.SelectContext(ctx, &members, query, projectID, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *PostgresProjectRepository) InsertComment(ctx context.Context, comment NewComment, mentionIDs []int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	var pointX, pointY *float64
+	if comment.Point != nil {
+		pointX, pointY = &comment.Point.X, &comment.Point.Y
+	}
+
+	query := `
+		INSERT INTO projects_comment (project_id, building_id, parent_id, author_id, text, point_x, point_y, is_resolved, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, false, now())
+		RETURNING id;
+	`
+	var commentID int64
+	err = tx.GetContext(ctx, &commentID, query, comment.ProjectID, comment.BuildingID, comment.ParentID, comment.AuthorID, comment.Text, pointX, pointY)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	for _, userID := range mentionIDs {
+		_, err = tx.ExecContext(ctx, `INSERT INTO projects_comment_mentions (comment_id, user_id) VALUES ($1, $2);`, commentID, userID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to add mention: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return commentID, nil
+}
+
+func (r *PostgresProjectRepository) SetCommentResolved(ctx context.Context, organisationID int64, commentID int64, resolved bool, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE projects_comment c
+		SET is_resolved = $1,
+			resolved_by_id = CASE WHEN $1 THEN $2::bigint END,
+			resolved_at = CASE WHEN $1 THEN now() END
+		FROM projects_project pr
+		WHERE pr.id = c.project_id AND c.id = $3 AND pr.organisation_id = $4;
+	`
+	_, err := r.db.ExecContext(ctx, query, resolved, userID, commentID, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	return nil
+}
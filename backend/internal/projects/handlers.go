@@ -1,13 +1,38 @@
 package projects
 
 import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/auth"
+	"3d-backend/internal/metrics"
+	"3d-backend/internal/organisations"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+// Config holds the project settings that come from the server configuration.
+type Config struct {
+	// ShareLinkSecret signs share link tokens, so forged tokens are rejected
+	// before touching the database.
+	ShareLinkSecret []byte
+	// EditLockTTL is how long a lock survives without activity from its holder.
+	EditLockTTL time.Duration
+}
+
+// Handler serves the project API on top of an injected ProjectRepository.
+type Handler struct {
+	projects ProjectRepository
+	cfg      Config
+}
+
+func NewHandler(projects ProjectRepository, cfg Config) *Handler {
+	return &Handler{projects: projects, cfg: cfg}
+}
+
 type Playground struct {
 	ID          int64        `db:"id" json:"id"`
 	ProjectID   int64        `db:"project_id" json:"project_id"`
@@ -23,12 +48,15 @@ type Building struct {
 }
 
 type projectDetailsResponse struct {
-	Buildings  []Building  `json:"buildings"`
-	Playground *Playground `json:"playground,omitempty"`
+	ID             int64       `json:"id"`
+	Name           string      `json:"name"`
+	OrganisationID int64       `json:"organisation_id"`
+	Buildings      []Building  `json:"buildings"`
+	Playground     *Playground `json:"playground,omitempty"`
 }
 
 type createProjectInput struct {
-	Name string `json:"name"`
+	Name string `json:"name" binding:"required"`
 }
 
 type createProjectResponse struct {
@@ -41,7 +69,7 @@ type Coordinate struct {
 }
 
 type createBuildingInput struct {
-	ProjectID   int64        `json:"project_id"`
+	ProjectID   int64        `json:"project_id" binding:"required"`
 	Coordinates []Coordinate `json:"coordinates"`
 }
 
@@ -50,7 +78,7 @@ type createBuildingResponse struct {
 }
 
 type createPlaygroundInput struct {
-	ProjectID   int64        `json:"project_id"`
+	ProjectID   int64        `json:"project_id" binding:"required"`
 	Coordinates []Coordinate `json:"coordinates"`
 }
 
@@ -75,88 +103,98 @@ type updatePlaygroundInput struct {
 // @Tags project
 // @Accept json
 // @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
 // @Param project_id query int true "Project ID"
 // @Success 200 {object} projectDetailsResponse "Project Details"
+// @Failure 404 {object} apperr.Response "Project not found"
+// @Security BearerAuth
 // @Router /project/project-details [get]
-func GetProject(c *gin.Context) {
+func (h *Handler) GetProject(c *gin.Context) {
 	projectIDParam := c.Query("project_id")
 	projectID, err := strconv.ParseInt(projectIDParam, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project id"})
+		apperr.Respond(c, apperr.InvalidField("project_id", "integer", "Invalid project_id"))
 		return
 	}
-	db := c.MustGet("db").(*sqlx.DB)
 
-	projectDetails, err := GetProjectDetails(db, projectID)
+	access, ok := h.authorizeProject(c, projectID, RoleViewer)
+	if !ok {
+		return
+	}
+
+	response, err := h.loadProjectDetails(c.Request.Context(), access.OrganisationID, projectID)
+	if errors.Is(err, ErrProjectNotFound) {
+		apperr.Respond(c, apperr.NotFound("Project not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project details"})
-		return
-	}
-
-	var buildings []Building
-	var playground *Playground
-
-	for _, row := range projectDetails {
-		if row.BuildingID.Valid {
-			var coord []Coordinate
-			err := json.Unmarshal([]byte(row.BuildingCoordinates.String), &coord)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unmarshalling coordinates"})
-				return
-			}
-			buildings = append(buildings, Building{
-				ID:           row.BuildingID.Int64,
-				Coordinates:  coord,
-				Floors:       int(row.BuildingFloors.Int64),
-				FloorsHeight: row.BuildingFloorsHeight.Float64,
-			})
-		}
-
-		if row.PlaygroundID.Valid && playground == nil {
-			var coord []Coordinate
-			err := json.Unmarshal([]byte(row.PlaygroundCoordinates.String), &coord)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unmarshalling coordinates"})
-				return
-			}
-			playground = &Playground{
-				ID:          row.PlaygroundID.Int64,
-				Coordinates: coord,
-			}
-		}
-	}
-
-	response := projectDetailsResponse{
-		Buildings:  buildings,
-		Playground: playground,
+		apperr.Respond(c, apperr.Internal("Failed to get project details").WithCause(err))
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// loadProjectDetails assembles the GetProject payload. It is shared by the
+// authenticated and the public share-link endpoints.
+func (h *Handler) loadProjectDetails(ctx context.Context, organisationID int64, projectID int64) (projectDetailsResponse, error) {
+	details, err := h.projects.GetProjectDetails(ctx, organisationID, projectID)
+	if err != nil {
+		return projectDetailsResponse{}, err
+	}
+
+	return projectDetailsResponse{
+		ID:             details.ID,
+		Name:           details.Name,
+		OrganisationID: details.OrganisationID,
+		Buildings:      details.Buildings,
+		Playground:     details.Playground,
+	}, nil
+}
+
 // CreateProject godoc
 // @Summary Создание проекта
 // @Tags project
 // @Accept json
 // @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
 // @Param input body createProjectInput true "Project information"
 // @Success 200 {object} createProjectResponse "Project Details"
+// @Security BearerAuth
 // @Router /project/create-project [post]
-func CreateProject(c *gin.Context) {
+func (h *Handler) CreateProject(c *gin.Context) {
 	var input createProjectInput
-	db := c.MustGet("db").(*sqlx.DB)
 
-	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid input"})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
 		return
 	}
 
-	projectID, err := InsertProject(db, input.Name, 1)
+	userID, err := auth.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create object"})
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return
+	}
+
+	if _, scoped := auth.GetAPIKeyProjectID(c); scoped {
+		apperr.Respond(c, apperr.Forbidden("API key is limited to a single project"))
 		return
 	}
 
+	organisationID, err := organisations.GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	projectID, err := h.projects.InsertProject(c.Request.Context(), organisationID, input.Name, userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed create object").WithCause(err))
+		return
+	}
+	c.Set("project_id", projectID)
+	metrics.ObjectsCreated.WithLabelValues("project").Inc()
+
 	c.JSON(http.StatusOK, createProjectResponse{
 		ProjectID: projectID,
 	})
@@ -167,29 +205,40 @@ func CreateProject(c *gin.Context) {
 // @Tags project
 // @Accept json
 // @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
 // @Param input body createBuildingInput true "Building information"
 // @Success 200 {object} createBuildingResponse "Building Details"
+// @Security BearerAuth
 // @Router /project/create-building [post]
-func CreateBuilding(c *gin.Context) {
+func (h *Handler) CreateBuilding(c *gin.Context) {
 	var input createBuildingInput
-	db := c.MustGet("db").(*sqlx.DB)
 
-	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid input"})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, input.ProjectID, RoleEditor)
+	if !ok {
 		return
 	}
 
 	coordinatesJSON, err := json.Marshal(input.Coordinates)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse coordinates"})
+		apperr.Respond(c, apperr.Internal("Failed to parse coordinates").WithCause(err))
 		return
 	}
 
-	buildingID, err := InsertBuilding(db, input.ProjectID, string(coordinatesJSON))
+	buildingID, err := h.projects.InsertBuilding(c.Request.Context(), access.OrganisationID, input.ProjectID, string(coordinatesJSON))
+	if errors.Is(err, ErrProjectNotFound) {
+		apperr.Respond(c, apperr.NotFound("Project not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create building"})
+		apperr.Respond(c, apperr.Internal("Failed create building").WithCause(err))
 		return
 	}
+	metrics.ObjectsCreated.WithLabelValues("building").Inc()
 
 	c.JSON(http.StatusOK, createBuildingResponse{
 		BuildingID: buildingID,
@@ -201,29 +250,40 @@ func CreateBuilding(c *gin.Context) {
 // @Tags project
 // @Accept json
 // @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
 // @Param input body createBuildingInput true "Playground information"
 // @Success 200 {object} createBuildingResponse
+// @Security BearerAuth
 // @Router /project/create-playground [post]
-func CreatePlayground(c *gin.Context) {
+func (h *Handler) CreatePlayground(c *gin.Context) {
 	var input createPlaygroundInput
-	db := c.MustGet("db").(*sqlx.DB)
 
-	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid input"})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, input.ProjectID, RoleEditor)
+	if !ok {
 		return
 	}
 
 	coordinatesJSON, err := json.Marshal(input.Coordinates)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse coordinates"})
+		apperr.Respond(c, apperr.Internal("Failed to parse coordinates").WithCause(err))
 		return
 	}
 
-	playgroundID, err := InsertPlayground(db, input.ProjectID, string(coordinatesJSON))
+	playgroundID, err := h.projects.InsertPlayground(c.Request.Context(), access.OrganisationID, input.ProjectID, string(coordinatesJSON))
+	if errors.Is(err, ErrProjectNotFound) {
+		apperr.Respond(c, apperr.NotFound("Project not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create playground"})
+		apperr.Respond(c, apperr.Internal("Failed create playground").WithCause(err))
 		return
 	}
+	metrics.ObjectsCreated.WithLabelValues("playground").Inc()
 
 	c.JSON(http.StatusOK, createPlaygroundResponse{
 		PlaygroundID: playgroundID,
@@ -235,26 +295,47 @@ func CreatePlayground(c *gin.Context) {
 // @Tags project
 // @Accept json
 // @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
 // @Param input body updateBuildingInput true "Building information"
+// @Failure 409 {object} apperr.Response "Locked by another user"
+// @Security BearerAuth
 // @Router /project/update-building [patch]
-func PatchBuilding(c *gin.Context) {
+func (h *Handler) PatchBuilding(c *gin.Context) {
 	var input updateBuildingInput
-	db := c.MustGet("db").(*sqlx.DB)
 
-	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid input"})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeObject(c, LockObjectBuilding, input.BuildingID, RoleEditor)
+	if !ok {
+		return
+	}
+
+	err := h.projects.CheckLock(c.Request.Context(), access.OrganisationID, LockObjectBuilding, input.BuildingID, access.UserID, h.cfg.EditLockTTL)
+	if errors.Is(err, ErrLockHeld) {
+		apperr.Respond(c, apperr.Conflict("Building is locked by another user"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to check lock").WithCause(err))
 		return
 	}
 
 	coordinatesJSON, err := json.Marshal(input.Coordinates)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse coordinates"})
+		apperr.Respond(c, apperr.Internal("Failed to parse coordinates").WithCause(err))
 		return
 	}
 
-	err = UpdateBuilding(db, input.BuildingID, string(coordinatesJSON), input.Floors, input.FloorsHeight)
+	err = h.projects.UpdateBuilding(c.Request.Context(), access.OrganisationID, input.BuildingID, string(coordinatesJSON), input.Floors, input.FloorsHeight)
+	if errors.Is(err, ErrProjectNotFound) {
+		apperr.Respond(c, apperr.NotFound("Building not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed update building"})
+		apperr.Respond(c, apperr.Internal("Failed update building").WithCause(err))
 		return
 	}
 
@@ -266,26 +347,47 @@ func PatchBuilding(c *gin.Context) {
 // @Tags project
 // @Accept json
 // @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
 // @Param input body updatePlaygroundInput true "Playground information"
+// @Failure 409 {object} apperr.Response "Locked by another user"
+// @Security BearerAuth
 // @Router /project/update-playground [patch]
-func PatchPlayground(c *gin.Context) {
+func (h *Handler) PatchPlayground(c *gin.Context) {
 	var input updatePlaygroundInput
-	db := c.MustGet("db").(*sqlx.DB)
 
-	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid input"})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeObject(c, LockObjectPlayground, input.PlaygroundID, RoleEditor)
+	if !ok {
+		return
+	}
+
+	err := h.projects.CheckLock(c.Request.Context(), access.OrganisationID, LockObjectPlayground, input.PlaygroundID, access.UserID, h.cfg.EditLockTTL)
+	if errors.Is(err, ErrLockHeld) {
+		apperr.Respond(c, apperr.Conflict("Playground is locked by another user"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to check lock").WithCause(err))
 		return
 	}
 
 	coordinatesJSON, err := json.Marshal(input.Coordinates)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse coordinates"})
+		apperr.Respond(c, apperr.Internal("Failed to parse coordinates").WithCause(err))
 		return
 	}
 
-	err = UpdatePlayground(db, input.PlaygroundID, string(coordinatesJSON))
+	err = h.projects.UpdatePlayground(c.Request.Context(), access.OrganisationID, input.PlaygroundID, string(coordinatesJSON))
+	if errors.Is(err, ErrProjectNotFound) {
+		apperr.Respond(c, apperr.NotFound("Playground not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed update playground"})
+		apperr.Respond(c, apperr.Internal("Failed update playground").WithCause(err))
 		return
 	}
 
@@ -0,0 +1,196 @@
+package projects
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingProjectRepository holds GetProjectDetails open until its context
+// ends, like a query waiting on a locked table.
+type blockingProjectRepository struct {
+	*MemoryProjectRepository
+	started chan struct{}
+	done    chan error
+}
+
+func (r *blockingProjectRepository) GetProjectDetails(ctx context.Context, organisationID int64, projectID int64) (ProjectDetails, error) {
+	close(r.started)
+	<-ctx.Done()
+	r.done <- ctx.Err()
+	return ProjectDetails{}, ctx.Err()
+}
+
+func TestGetProjectAbortsWhenClientDisconnects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const organisationID, userID = 1, 2
+	memory := NewMemoryProjectRepository()
+	memory.AddUser(userID, "alice")
+	memory.AddOrganisationMember(organisationID, userID)
+	projectID, err := memory.InsertProject(context.Background(), organisationID, "Site", userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projects := &blockingProjectRepository{
+		MemoryProjectRepository: memory,
+		started:                 make(chan struct{}),
+		done:                    make(chan error, 1),
+	}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", strconv.FormatInt(userID, 10))
+		c.Set("organisation_id", int64(organisationID))
+	})
+	r.GET("/project/project-details", NewHandler(projects, Config{}).GetProject)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := httptest.NewRecorder()
+	url := "/project/project-details?project_id=" + strconv.FormatInt(projectID, 10)
+	req := httptest.NewRequest(http.MethodGet, url, nil).WithContext(ctx)
+
+	served := make(chan struct{})
+	go func() {
+		r.ServeHTTP(w, req)
+		close(served)
+	}()
+
+	<-projects.started
+	cancel()
+
+	select {
+	case err := <-projects.done:
+		if err != context.Canceled {
+			t.Errorf("query ended with %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("query was not aborted")
+	}
+	<-served
+
+	if w.Code != 499 {
+		t.Errorf("status = %d, want 499", w.Code)
+	}
+}
+
+func TestOtherOrganisationsIDsLookMissing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const aliceOrganisationID, aliceID = 1, 2
+	const bobOrganisationID, bobID = 3, 4
+	repo := NewMemoryProjectRepository()
+	repo.AddUser(aliceID, "alice")
+	repo.AddUser(bobID, "bob")
+	repo.AddOrganisationMember(aliceOrganisationID, aliceID)
+	repo.AddOrganisationMember(bobOrganisationID, bobID)
+
+	ctx := context.Background()
+	projectID, err := repo.InsertProject(ctx, aliceOrganisationID, "Site", aliceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentID, err := repo.InsertComment(ctx, NewComment{ProjectID: projectID, AuthorID: aliceID, Text: "Hi"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkID, err := repo.InsertShareLink(ctx, projectID, "hash", "", aliceID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(repo, Config{})
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", strconv.FormatInt(bobID, 10))
+		c.Set("organisation_id", int64(bobOrganisationID))
+	})
+	r.POST("/project/resolve-comment", h.ResolveComment)
+	r.POST("/project/revoke-share-link", h.RevokeShareLink)
+
+	post := func(url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, strings.NewReader(body)))
+		return w
+	}
+
+	tests := []struct {
+		url   string
+		field string
+		id    int64
+	}{
+		{"/project/resolve-comment", "comment_id", commentID},
+		{"/project/revoke-share-link", "share_link_id", linkID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			other := post(tt.url, `{"`+tt.field+`": `+strconv.FormatInt(tt.id, 10)+`}`)
+			missing := post(tt.url, `{"`+tt.field+`": 1000}`)
+			if other.Code != http.StatusNotFound || other.Code != missing.Code || other.Body.String() != missing.Body.String() {
+				t.Errorf("other organisation: %d %s, missing: %d %s", other.Code, other.Body, missing.Code, missing.Body)
+			}
+		})
+	}
+
+	comment, err := repo.GetCommentRow(ctx, aliceOrganisationID, commentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.IsResolved {
+		t.Error("comment of another organisation was resolved")
+	}
+	link, err := repo.GetShareLink(ctx, aliceOrganisationID, linkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.RevokedAt != nil {
+		t.Error("share link of another organisation was revoked")
+	}
+}
+
+// failingShareLinkRepository fails share link lookups like a database that
+// timed out.
+type failingShareLinkRepository struct {
+	*MemoryProjectRepository
+}
+
+func (failingShareLinkRepository) GetShareLinkByTokenHash(ctx context.Context, tokenHash string) (ShareLink, error) {
+	return ShareLink{}, context.DeadlineExceeded
+}
+
+func TestGetSharedProjectErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	token, err := newShareToken(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		projects   ProjectRepository
+		wantStatus int
+	}{
+		{"unknown link", NewMemoryProjectRepository(), http.StatusUnauthorized},
+		{"repository error", failingShareLinkRepository{NewMemoryProjectRepository()}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/share/project-details", NewHandler(tt.projects, Config{ShareLinkSecret: secret}).GetSharedProject)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/share/project-details?token="+token, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package projects
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/organisations"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type lockInput struct {
+	ObjectType string `json:"object_type" binding:"required,oneof=building playground" example:"building"`
+	ObjectID   int64  `json:"object_id" binding:"required"`
+}
+
+type lockConflictResponse struct {
+	Error string      `json:"error"`
+	Code  apperr.Code `json:"code" example:"conflict"`
+	Lock  EditLock    `json:"lock"`
+}
+
+type breakLockInput struct {
+	LockID int64 `json:"lock_id" binding:"required"`
+}
+
+// LockObject godoc
+// @Summary Захват здания или площадки для редактирования
+// @Tags project
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body lockInput true "Object to lock"
+// @Success 200 {object} EditLock "Lock"
+// @Failure 409 {object} lockConflictResponse "Locked by another user"
+// @Security BearerAuth
+// @Router /project/lock [post]
+func (h *Handler) LockObject(c *gin.Context) {
+	var input lockInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeObject(c, input.ObjectType, input.ObjectID, RoleEditor)
+	if !ok {
+		return
+	}
+
+	lock, err := h.projects.AcquireLock(c.Request.Context(), access.OrganisationID, access.ProjectID, input.ObjectType, input.ObjectID, access.UserID, h.cfg.EditLockTTL)
+	if errors.Is(err, ErrLockHeld) {
+		c.JSON(http.StatusConflict, lockConflictResponse{
+			Error: "Object is locked by another user",
+			Code:  apperr.CodeConflict,
+			Lock:  lock,
+		})
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to acquire lock").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// UnlockObject godoc
+// @Summary Освобождение здания или площадки
+// @Tags project
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body lockInput true "Object to unlock"
+// @Security BearerAuth
+// @Router /project/unlock [post]
+func (h *Handler) UnlockObject(c *gin.Context) {
+	var input lockInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeObject(c, input.ObjectType, input.ObjectID, RoleEditor)
+	if !ok {
+		return
+	}
+
+	err := h.projects.ReleaseLock(c.Request.Context(), access.OrganisationID, input.ObjectType, input.ObjectID, access.UserID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to release lock").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// ListLocks godoc
+// @Summary Список активных блокировок
+// @Tags admin
+// @Accept */*
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Success 200 {array} EditLock "Active locks"
+// @Security BearerAuth
+// @Router /admin/locks [get]
+func (h *Handler) ListLocks(c *gin.Context) {
+	organisationID, err := organisations.GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	locks, err := h.projects.ListActiveLocks(c.Request.Context(), organisationID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get locks").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, locks)
+}
+
+// BreakLock godoc
+// @Summary Принудительное снятие блокировки
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body breakLockInput true "Lock to break"
+// @Security BearerAuth
+// @Router /admin/break-lock [post]
+func (h *Handler) BreakLock(c *gin.Context) {
+	var input breakLockInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	organisationID, err := organisations.GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	err = h.projects.DeleteLock(c.Request.Context(), organisationID, input.LockID)
+	if errors.Is(err, ErrLockNotFound) {
+		apperr.Respond(c, apperr.NotFound("Lock not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to break lock").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
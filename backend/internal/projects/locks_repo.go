@@ -0,0 +1,217 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	LockObjectBuilding   = "building"
+	LockObjectPlayground = "playground"
+)
+
+var (
+	ErrLockHeld     = errors.New("object is locked by another user")
+	ErrLockNotFound = errors.New("lock not found")
+)
+
+type EditLock struct {
+	ID         int64     `db:"id" json:"id"`
+	ProjectID  int64     `db:"project_id" json:"project_id"`
+	ObjectType string    `db:"object_type" json:"object_type"`
+	ObjectID   int64     `db:"object_id" json:"object_id"`
+	UserID     int64     `db:"user_id" json:"user_id"`
+	Username   string    `db:"username" json:"username"`
+	AcquiredAt time.Time `db:"acquired_at" json:"acquired_at"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expires_at"`
+}
+
+func (r *PostgresProjectRepository) GetObjectProjectID(ctx context.Context, organisationID int64, objectType string, objectID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var query string
+	switch objectType {
+	case LockObjectBuilding:
+		query = `
+			SELECT b.project_id
+			FROM projects_building b
+			JOIN projects_project pr ON pr.id = b.project_id
+			WHERE b.id = $1 AND pr.organisation_id = $2
+		`
+	case LockObjectPlayground:
+		query = `
+			SELECT p.project_id
+			FROM projects_playground p
+			JOIN projects_project pr ON pr.id = p.project_id
+			WHERE p.id = $1 AND pr.organisation_id = $2
+		`
+	default:
+		return 0, fmt.Errorf("unknown object type: %s", objectType)
+	}
+
+	var projectID int64
+	err := r.db.GetContext(ctx, &projectID, query, objectID, organisationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrProjectNotFound
+		}
+		return 0, err
+	}
+	return projectID, nil
+}
+
+func (r *PostgresProjectRepository) GetActiveLock(ctx context.Context, organisationID int64, objectType string, objectID int64) (*EditLock, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT l.id, l.project_id, l.object_type, l.object_id, l.user_id, u.username, l.acquired_at, l.expires_at
+		FROM projects_editlock l
+		JOIN projects_project pr ON pr.id = l.project_id
+		JOIN auth_user u ON u.id = l.user_id
+		WHERE l.object_type = $1 AND l.object_id = $2 AND l.expires_at > now() AND pr.organisation_id = $3;
+	`
+	var lock EditLock
+	err := r.db.GetContext(ctx, &lock, query, objectType, objectID, organisationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// AcquireLock claims the object for userID. An expired lock or a lock already
+// held by the same user is taken over; otherwise the current lock is returned
+// together with ErrLockHeld. ttl is how long the lock survives without
+// activity from its holder.
+func (r *PostgresProjectRepository) AcquireLock(ctx context.Context, organisationID int64, projectID int64, objectType string, objectID int64, userID int64, ttl time.Duration) (EditLock, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO projects_editlock (project_id, object_type, object_id, user_id, acquired_at, expires_at)
+		VALUES ($1, $2, $3, $4, now(), now() + $5 * interval '1 second')
+		ON CONFLICT (object_type, object_id) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+			acquired_at = CASE
+				WHEN projects_editlock.user_id = EXCLUDED.user_id AND projects_editlock.expires_at > now()
+				THEN projects_editlock.acquired_at
+				ELSE EXCLUDED.acquired_at
+			END,
+			expires_at = EXCLUDED.expires_at
+		WHERE projects_editlock.user_id = EXCLUDED.user_id OR projects_editlock.expires_at <= now()
+		RETURNING id;
+	`
+	var lockID int64
+	err := r.db.GetContext(ctx, &lockID, query, projectID, objectType, objectID, userID, ttl.Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return EditLock{}, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	lock, err := r.GetActiveLock(ctx, organisationID, objectType, objectID)
+	if err != nil {
+		return EditLock{}, fmt.Errorf("failed to get lock: %w", err)
+	}
+	if lock == nil {
+		return EditLock{}, fmt.Errorf("lock for %s %d disappeared", objectType, objectID)
+	}
+	if lock.UserID != userID {
+		return *lock, ErrLockHeld
+	}
+	return *lock, nil
+}
+
+func (r *PostgresProjectRepository) ReleaseLock(ctx context.Context, organisationID int64, objectType string, objectID int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM projects_editlock l
+		USING projects_project pr
+		WHERE pr.id = l.project_id AND l.object_type = $1 AND l.object_id = $2 AND l.user_id = $3 AND pr.organisation_id = $4;
+	`
+	_, err := r.db.ExecContext(ctx, query, objectType, objectID, userID, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// CheckLock returns ErrLockHeld when another user holds an active lock on the
+// object. If userID holds the lock itself, its expiry is pushed ttl forward.
+// The lock row stays locked from the check to the extension, so no one can
+// take the lock in between.
+func (r *PostgresProjectRepository) CheckLock(ctx context.Context, organisationID int64, objectType string, objectID int64, userID int64, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH current_lock AS (
+			SELECT l.id, l.user_id
+			FROM projects_editlock l
+			JOIN projects_project pr ON pr.id = l.project_id
+			WHERE l.object_type = $1 AND l.object_id = $2 AND l.expires_at > now() AND pr.organisation_id = $5
+			FOR UPDATE OF l
+		), extended AS (
+			UPDATE projects_editlock l
+			SET expires_at = now() + $4 * interval '1 second'
+			FROM current_lock cl
+			WHERE l.id = cl.id AND cl.user_id = $3
+		)
+		SELECT EXISTS (SELECT 1 FROM current_lock WHERE user_id <> $3);
+	`
+	var held bool
+	err := r.db.GetContext(ctx, &held, query, objectType, objectID, userID, ttl.Seconds(), organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to check lock: %w", err)
+	}
+	if held {
+		return ErrLockHeld
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepository) ListActiveLocks(ctx context.Context, organisationID int64) ([]EditLock, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT l.id, l.project_id, l.object_type, l.object_id, l.user_id, u.username, l.acquired_at, l.expires_at
+		FROM projects_editlock l
+		JOIN projects_project pr ON pr.id = l.project_id
+		JOIN auth_user u ON u.id = l.user_id
+		WHERE l.expires_at > now() AND pr.organisation_id = $1
+		ORDER BY l.project_id, l.acquired_at;
+	`
+	locks := []EditLock{}
+	err := r.db.SelectContext(ctx, &locks, query, organisationID)
+	if err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+func (r *PostgresProjectRepository) DeleteLock(ctx context.Context, organisationID int64, lockID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM projects_editlock l
+		USING projects_project pr
+		WHERE pr.id = l.project_id AND l.id = $1 AND pr.organisation_id = $2;
+	`
+	res, err := r.db.ExecContext(ctx, query, lockID, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete lock: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrLockNotFound
+	}
+	return nil
+}
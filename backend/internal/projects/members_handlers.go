@@ -0,0 +1,296 @@
+package projects
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/auth"
+	"3d-backend/internal/organisations"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type inviteMemberInput struct {
+	ProjectID int64  `json:"project_id" binding:"required"`
+	Username  string `json:"username" binding:"required"`
+	Role      string `json:"role" binding:"required,oneof=owner editor viewer" example:"editor"`
+}
+
+type updateMemberRoleInput struct {
+	ProjectID int64  `json:"project_id" binding:"required"`
+	UserID    int64  `json:"user_id" binding:"required"`
+	Role      string `json:"role" binding:"required,oneof=owner editor viewer" example:"viewer"`
+}
+
+type removeMemberInput struct {
+	ProjectID int64 `json:"project_id" binding:"required"`
+	UserID    int64 `json:"user_id" binding:"required"`
+}
+
+// projectAccess describes who is acting on which project of which tenant.
+type projectAccess struct {
+	UserID         int64
+	OrganisationID int64
+	ProjectID      int64
+	Role           string
+}
+
+// authorizeProject checks that the current user has at least the required
+// role in a project of the current organisation. Organisation admins can view
+// every project of their organisation, project-scoped API keys only reach
+// their project. On failure the response is already written.
+func (h *Handler) authorizeProject(c *gin.Context, projectID int64, required string) (projectAccess, bool) {
+	// Logged with the request, also when access is denied.
+	c.Set("project_id", projectID)
+
+	userID, err := auth.GetUserID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Unauthorized"))
+		return projectAccess{}, false
+	}
+
+	if scopedProjectID, scoped := auth.GetAPIKeyProjectID(c); scoped && scopedProjectID != projectID {
+		apperr.Respond(c, apperr.Forbidden("API key is limited to another project"))
+		return projectAccess{}, false
+	}
+
+	organisationID, err := organisations.GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return projectAccess{}, false
+	}
+
+	role, err := h.projects.GetProjectRole(c.Request.Context(), organisationID, projectID, userID)
+	if errors.Is(err, ErrNotMember) {
+		exists, existsErr := h.projects.ProjectExists(c.Request.Context(), organisationID, projectID)
+		if existsErr != nil {
+			apperr.Respond(c, apperr.Internal("Failed to get project").WithCause(existsErr))
+			return projectAccess{}, false
+		}
+		if !exists {
+			apperr.Respond(c, apperr.NotFound("Project not found"))
+			return projectAccess{}, false
+		}
+		if organisations.IsAdmin(c) {
+			role, err = RoleViewer, nil
+		}
+	}
+	if errors.Is(err, ErrNotMember) {
+		apperr.Respond(c, apperr.Forbidden("Access to project denied"))
+		return projectAccess{}, false
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get project role").WithCause(err))
+		return projectAccess{}, false
+	}
+
+	if !RoleAllows(role, required) {
+		apperr.Respond(c, apperr.Forbidden("Insufficient project role"))
+		return projectAccess{}, false
+	}
+
+	return projectAccess{
+		UserID:         userID,
+		OrganisationID: organisationID,
+		ProjectID:      projectID,
+		Role:           role,
+	}, true
+}
+
+// authorizeObject resolves the project of a building or playground and checks
+// the current user's role in it.
+func (h *Handler) authorizeObject(c *gin.Context, objectType string, objectID int64, required string) (projectAccess, bool) {
+	organisationID, err := organisations.GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return projectAccess{}, false
+	}
+
+	projectID, err := h.projects.GetObjectProjectID(c.Request.Context(), organisationID, objectType, objectID)
+	if errors.Is(err, ErrProjectNotFound) {
+		apperr.Respond(c, apperr.NotFound("Object not found"))
+		return projectAccess{}, false
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get object").WithCause(err))
+		return projectAccess{}, false
+	}
+
+	return h.authorizeProject(c, projectID, required)
+}
+
+// ListMembers godoc
+// @Summary Участники проекта
+// @Tags members
+// @Accept */*
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param project_id query int true "Project ID"
+// @Success 200 {array} Member "Project members"
+// @Security BearerAuth
+// @Router /project/members [get]
+func (h *Handler) ListMembers(c *gin.Context) {
+	projectID, err := strconv.ParseInt(c.Query("project_id"), 10, 64)
+	if err != nil {
+		apperr.Respond(c, apperr.InvalidField("project_id", "integer", "Invalid project_id"))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, projectID, RoleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := h.projects.GetProjectMembers(c.Request.Context(), access.OrganisationID, projectID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get members").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// InviteMember godoc
+// @Summary Приглашение пользователя в проект
+// @Tags members
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body inviteMemberInput true "Member information"
+// @Success 200 {object} Member "Added member"
+// @Security BearerAuth
+// @Router /project/invite-member [post]
+func (h *Handler) InviteMember(c *gin.Context) {
+	var input inviteMemberInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, input.ProjectID, RoleOwner)
+	if !ok {
+		return
+	}
+
+	member, err := h.projects.InsertProjectMember(c.Request.Context(), access.OrganisationID, input.ProjectID, input.Username, input.Role)
+	if errors.Is(err, ErrNotOrganisationMember) {
+		apperr.Respond(c, apperr.InvalidField("username", "organisation_member", "User is not a member of the organisation"))
+		return
+	}
+	if errors.Is(err, ErrAlreadyMember) {
+		apperr.Respond(c, apperr.Conflict("User is already a project member"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to add member").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// UpdateMemberRole godoc
+// @Summary Изменение роли участника
+// @Tags members
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body updateMemberRoleInput true "Member role"
+// @Security BearerAuth
+// @Router /project/update-member-role [post]
+func (h *Handler) UpdateMemberRole(c *gin.Context) {
+	var input updateMemberRoleInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, input.ProjectID, RoleOwner)
+	if !ok {
+		return
+	}
+
+	if input.Role != RoleOwner && !h.keepsAnOwner(c, access, input.UserID) {
+		return
+	}
+
+	err := h.projects.UpdateProjectMemberRole(c.Request.Context(), access.OrganisationID, input.ProjectID, input.UserID, input.Role)
+	if errors.Is(err, ErrNotMember) {
+		apperr.Respond(c, apperr.NotFound("Member not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to update member").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// RemoveMember godoc
+// @Summary Удаление участника из проекта
+// @Tags members
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body removeMemberInput true "Member"
+// @Security BearerAuth
+// @Router /project/remove-member [post]
+func (h *Handler) RemoveMember(c *gin.Context) {
+	var input removeMemberInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, input.ProjectID, RoleOwner)
+	if !ok {
+		return
+	}
+
+	if !h.keepsAnOwner(c, access, input.UserID) {
+		return
+	}
+
+	err := h.projects.DeleteProjectMember(c.Request.Context(), access.OrganisationID, input.ProjectID, input.UserID)
+	if errors.Is(err, ErrNotMember) {
+		apperr.Respond(c, apperr.NotFound("Member not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to remove member").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// keepsAnOwner refuses to demote or remove the last owner of a project.
+func (h *Handler) keepsAnOwner(c *gin.Context, access projectAccess, userID int64) bool {
+	role, err := h.projects.GetProjectRole(c.Request.Context(), access.OrganisationID, access.ProjectID, userID)
+	if errors.Is(err, ErrNotMember) {
+		apperr.Respond(c, apperr.NotFound("Member not found"))
+		return false
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get member").WithCause(err))
+		return false
+	}
+	if role != RoleOwner {
+		return true
+	}
+
+	owners, err := h.projects.CountProjectOwners(c.Request.Context(), access.OrganisationID, access.ProjectID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get owners").WithCause(err))
+		return false
+	}
+	if owners <= 1 {
+		apperr.Respond(c, apperr.Conflict("Project must keep at least one owner"))
+		return false
+	}
+	return true
+}
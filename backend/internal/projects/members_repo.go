@@ -0,0 +1,166 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+var (
+	ErrNotMember             = errors.New("user is not a project member")
+	ErrAlreadyMember         = errors.New("user is already a project member")
+	ErrNotOrganisationMember = errors.New("user is not an organisation member")
+)
+
+type Member struct {
+	UserID   int64  `db:"user_id" json:"user_id"`
+	Username string `db:"username" json:"username"`
+	Role     string `db:"role" json:"role"`
+}
+
+// RoleAllows reports whether role grants at least the permissions of required.
+func RoleAllows(role, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+func (r *PostgresProjectRepository) GetProjectRole(ctx context.Context, organisationID int64, projectID int64, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var role string
+	query := `
+		SELECT pu.role
+		FROM projects_project_user pu
+		JOIN projects_project pr ON pr.id = pu.project_id
+		WHERE pu.project_id = $1 AND pu.user_id = $2 AND pr.organisation_id = $3;
+	`
+	err := r.db.GetContext(ctx, &role, query, projectID, userID, organisationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *PostgresProjectRepository) GetProjectMembers(ctx context.Context, organisationID int64, projectID int64) ([]Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	members := []Member{}
+	query := `
+		SELECT pu.user_id, u.username, pu.role
+		FROM projects_project_user pu
+		JOIN projects_project pr ON pr.id = pu.project_id
+		JOIN auth_user u ON u.id = pu.user_id
+		WHERE pu.project_id = $1 AND pr.organisation_id = $2
+		ORDER BY u.username;
+	`
+	err := r.db.SelectContext(ctx, &members, query, projectID, organisationID)
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// InsertProjectMember adds an existing user to the project. Only members of the
+// project's organisation can be invited.
+func (r *PostgresProjectRepository) InsertProjectMember(ctx context.Context, organisationID int64, projectID int64, username string, role string) (Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO projects_project_user (project_id, user_id, role)
+		SELECT pr.id, u.id, $3
+		FROM auth_user u
+		JOIN projects_organisationmember om ON om.user_id = u.id
+		JOIN projects_project pr ON pr.organisation_id = om.organisation_id
+		WHERE u.username = $2 AND pr.id = $1 AND om.organisation_id = $4
+		RETURNING user_id, $2::varchar AS username, role;
+	`
+	var member Member
+	err := r.db.GetContext(ctx, &member, query, projectID, username, role, organisationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return member, ErrNotOrganisationMember
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return member, ErrAlreadyMember
+		}
+		return member, fmt.Errorf("failed to add project member: %w", err)
+	}
+	return member, nil
+}
+
+func (r *PostgresProjectRepository) CountProjectOwners(ctx context.Context, organisationID int64, projectID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var count int
+	query := `
+		SELECT count(*)
+		FROM projects_project_user pu
+		JOIN projects_project pr ON pr.id = pu.project_id
+		WHERE pu.project_id = $1 AND pu.role = $2 AND pr.organisation_id = $3;
+	`
+	err := r.db.GetContext(ctx, &count, query, projectID, RoleOwner, organisationID)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PostgresProjectRepository) UpdateProjectMemberRole(ctx context.Context, organisationID int64, projectID int64, userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE projects_project_user pu
+		SET role = $1
+		FROM projects_project pr
+		WHERE pr.id = pu.project_id AND pu.project_id = $2 AND pu.user_id = $3 AND pr.organisation_id = $4;
+	`
+	res, err := r.db.ExecContext(ctx, query, role, projectID, userID, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to update project member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepository) DeleteProjectMember(ctx context.Context, organisationID int64, projectID int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM projects_project_user pu
+		USING projects_project pr
+		WHERE pr.id = pu.project_id AND pu.project_id = $1 AND pu.user_id = $2 AND pr.organisation_id = $3;
+	`
+	res, err := r.db.ExecContext(ctx, query, projectID, userID, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to remove project member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
@@ -0,0 +1,662 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryProject struct {
+	ID             int64
+	OrganisationID int64
+	Name           string
+	Members        map[int64]string
+}
+
+type memoryBuilding struct {
+	ID           int64
+	ProjectID    int64
+	Coordinates  string
+	Floors       int64
+	FloorsHeight float64
+}
+
+type memoryPlayground struct {
+	ID          int64
+	ProjectID   int64
+	Coordinates string
+}
+
+type memoryLockKey struct {
+	ObjectType string
+	ObjectID   int64
+}
+
+type memoryShareLink struct {
+	ShareLink
+	TokenHash string
+}
+
+// MemoryProjectRepository is a ProjectRepository that keeps everything in maps
+// behind a mutex. It follows the rules of PostgresProjectRepository and is
+// meant for tests. Users and organisation memberships live in the auth and
+// organisations tables, so tests seed them with AddUser and
+// AddOrganisationMember.
+type MemoryProjectRepository struct {
+	mu                  sync.Mutex
+	nextID              int64
+	usernames           map[int64]string
+	organisationMembers map[int64][]int64
+	projects            map[int64]*memoryProject
+	buildings           map[int64]*memoryBuilding
+	playgrounds         map[int64]*memoryPlayground
+	locks               map[memoryLockKey]*EditLock
+	comments            map[int64]*CommentRow
+	shareLinks          map[int64]*memoryShareLink
+}
+
+func NewMemoryProjectRepository() *MemoryProjectRepository {
+	return &MemoryProjectRepository{
+		usernames:           map[int64]string{},
+		organisationMembers: map[int64][]int64{},
+		projects:            map[int64]*memoryProject{},
+		buildings:           map[int64]*memoryBuilding{},
+		playgrounds:         map[int64]*memoryPlayground{},
+		locks:               map[memoryLockKey]*EditLock{},
+		comments:            map[int64]*CommentRow{},
+		shareLinks:          map[int64]*memoryShareLink{},
+	}
+}
+
+func (r *MemoryProjectRepository) AddUser(userID int64, username string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.usernames[userID] = username
+}
+
+func (r *MemoryProjectRepository) AddOrganisationMember(organisationID int64, userID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.organisationMembers[organisationID] = append(r.organisationMembers[organisationID], userID)
+}
+
+func (r *MemoryProjectRepository) newID() int64 {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *MemoryProjectRepository) project(organisationID int64, projectID int64) (*memoryProject, bool) {
+	project, ok := r.projects[projectID]
+	if !ok || project.OrganisationID != organisationID {
+		return nil, false
+	}
+	return project, true
+}
+
+func (r *MemoryProjectRepository) GetProjectDetails(ctx context.Context, organisationID int64, projectID int64) (ProjectDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.project(organisationID, projectID)
+	if !ok {
+		return ProjectDetails{}, ErrProjectNotFound
+	}
+
+	details := ProjectDetails{
+		ID:             project.ID,
+		Name:           project.Name,
+		OrganisationID: project.OrganisationID,
+		Buildings:      []Building{},
+	}
+	for _, b := range r.sortedBuildings(projectID) {
+		building := Building{ID: b.ID, ProjectID: b.ProjectID, Floors: int(b.Floors), FloorsHeight: b.FloorsHeight}
+		if err := json.Unmarshal([]byte(b.Coordinates), &building.Coordinates); err != nil {
+			return ProjectDetails{}, fmt.Errorf("failed to decode building coordinates: %w", err)
+		}
+		details.Buildings = append(details.Buildings, building)
+	}
+
+	// Postgres allows one playground per project.
+	if playgrounds := r.sortedPlaygrounds(projectID); len(playgrounds) > 0 {
+		p := playgrounds[0]
+		details.Playground = &Playground{ID: p.ID, ProjectID: p.ProjectID}
+		if err := json.Unmarshal([]byte(p.Coordinates), &details.Playground.Coordinates); err != nil {
+			return ProjectDetails{}, fmt.Errorf("failed to decode playground coordinates: %w", err)
+		}
+	}
+	return details, nil
+}
+
+func (r *MemoryProjectRepository) sortedBuildings(projectID int64) []memoryBuilding {
+	buildings := []memoryBuilding{}
+	for _, b := range r.buildings {
+		if b.ProjectID == projectID {
+			buildings = append(buildings, *b)
+		}
+	}
+	sort.Slice(buildings, func(i, j int) bool { return buildings[i].ID < buildings[j].ID })
+	return buildings
+}
+
+func (r *MemoryProjectRepository) sortedPlaygrounds(projectID int64) []memoryPlayground {
+	playgrounds := []memoryPlayground{}
+	for _, p := range r.playgrounds {
+		if p.ProjectID == projectID {
+			playgrounds = append(playgrounds, *p)
+		}
+	}
+	sort.Slice(playgrounds, func(i, j int) bool { return playgrounds[i].ID < playgrounds[j].ID })
+	return playgrounds
+}
+
+func (r *MemoryProjectRepository) InsertProject(ctx context.Context, organisationID int64, name string, userID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	projectID := r.newID()
+	r.projects[projectID] = &memoryProject{
+		ID:             projectID,
+		OrganisationID: organisationID,
+		Name:           name,
+		Members:        map[int64]string{userID: RoleOwner},
+	}
+	return projectID, nil
+}
+
+func (r *MemoryProjectRepository) InsertBuilding(ctx context.Context, organisationID int64, projectID int64, coordinates string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.project(organisationID, projectID); !ok {
+		return 0, ErrProjectNotFound
+	}
+	buildingID := r.newID()
+	r.buildings[buildingID] = &memoryBuilding{
+		ID:           buildingID,
+		ProjectID:    projectID,
+		Coordinates:  coordinates,
+		Floors:       1,
+		FloorsHeight: 3,
+	}
+	return buildingID, nil
+}
+
+func (r *MemoryProjectRepository) InsertPlayground(ctx context.Context, organisationID int64, projectID int64, coordinates string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.project(organisationID, projectID); !ok {
+		return 0, ErrProjectNotFound
+	}
+	playgroundID := r.newID()
+	r.playgrounds[playgroundID] = &memoryPlayground{
+		ID:          playgroundID,
+		ProjectID:   projectID,
+		Coordinates: coordinates,
+	}
+	return playgroundID, nil
+}
+
+func (r *MemoryProjectRepository) UpdateBuilding(ctx context.Context, organisationID int64, buildingID int64, coordinates string, floors int64, floorsHeight float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.buildings[buildingID]
+	if !ok {
+		return ErrProjectNotFound
+	}
+	if _, ok := r.project(organisationID, b.ProjectID); !ok {
+		return ErrProjectNotFound
+	}
+	b.Coordinates = coordinates
+	b.Floors = floors
+	b.FloorsHeight = floorsHeight
+	return nil
+}
+
+func (r *MemoryProjectRepository) UpdatePlayground(ctx context.Context, organisationID int64, playgroundID int64, coordinates string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.playgrounds[playgroundID]
+	if !ok {
+		return ErrProjectNotFound
+	}
+	if _, ok := r.project(organisationID, p.ProjectID); !ok {
+		return ErrProjectNotFound
+	}
+	p.Coordinates = coordinates
+	return nil
+}
+
+func (r *MemoryProjectRepository) ProjectExists(ctx context.Context, organisationID int64, projectID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.project(organisationID, projectID)
+	return ok, nil
+}
+
+func (r *MemoryProjectRepository) GetProjectRole(ctx context.Context, organisationID int64, projectID int64, userID int64) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.project(organisationID, projectID)
+	if !ok {
+		return "", ErrNotMember
+	}
+	role, ok := project.Members[userID]
+	if !ok {
+		return "", ErrNotMember
+	}
+	return role, nil
+}
+
+func (r *MemoryProjectRepository) GetProjectMembers(ctx context.Context, organisationID int64, projectID int64) ([]Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := []Member{}
+	if project, ok := r.project(organisationID, projectID); ok {
+		for userID, role := range project.Members {
+			members = append(members, Member{UserID: userID, Username: r.usernames[userID], Role: role})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Username < members[j].Username })
+	return members, nil
+}
+
+func (r *MemoryProjectRepository) InsertProjectMember(ctx context.Context, organisationID int64, projectID int64, username string, role string) (Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.project(organisationID, projectID)
+	if !ok {
+		return Member{}, ErrNotOrganisationMember
+	}
+	for userID, name := range r.usernames {
+		if name != username || !slices.Contains(r.organisationMembers[organisationID], userID) {
+			continue
+		}
+		if _, ok := project.Members[userID]; ok {
+			return Member{}, ErrAlreadyMember
+		}
+		project.Members[userID] = role
+		return Member{UserID: userID, Username: username, Role: role}, nil
+	}
+	return Member{}, ErrNotOrganisationMember
+}
+
+func (r *MemoryProjectRepository) CountProjectOwners(ctx context.Context, organisationID int64, projectID int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	if project, ok := r.project(organisationID, projectID); ok {
+		for _, role := range project.Members {
+			if role == RoleOwner {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryProjectRepository) UpdateProjectMemberRole(ctx context.Context, organisationID int64, projectID int64, userID int64, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.project(organisationID, projectID)
+	if !ok {
+		return ErrNotMember
+	}
+	if _, ok := project.Members[userID]; !ok {
+		return ErrNotMember
+	}
+	project.Members[userID] = role
+	return nil
+}
+
+func (r *MemoryProjectRepository) DeleteProjectMember(ctx context.Context, organisationID int64, projectID int64, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, ok := r.project(organisationID, projectID)
+	if !ok {
+		return ErrNotMember
+	}
+	if _, ok := project.Members[userID]; !ok {
+		return ErrNotMember
+	}
+	delete(project.Members, userID)
+	return nil
+}
+
+func (r *MemoryProjectRepository) GetObjectProjectID(ctx context.Context, organisationID int64, objectType string, objectID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var projectID int64
+	switch objectType {
+	case LockObjectBuilding:
+		b, ok := r.buildings[objectID]
+		if !ok {
+			return 0, ErrProjectNotFound
+		}
+		projectID = b.ProjectID
+	case LockObjectPlayground:
+		p, ok := r.playgrounds[objectID]
+		if !ok {
+			return 0, ErrProjectNotFound
+		}
+		projectID = p.ProjectID
+	default:
+		return 0, fmt.Errorf("unknown object type: %s", objectType)
+	}
+
+	if _, ok := r.project(organisationID, projectID); !ok {
+		return 0, ErrProjectNotFound
+	}
+	return projectID, nil
+}
+
+func (r *MemoryProjectRepository) GetActiveLock(ctx context.Context, organisationID int64, objectType string, objectID int64) (*EditLock, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.activeLock(organisationID, objectType, objectID), nil
+}
+
+func (r *MemoryProjectRepository) activeLock(organisationID int64, objectType string, objectID int64) *EditLock {
+	lock, ok := r.locks[memoryLockKey{ObjectType: objectType, ObjectID: objectID}]
+	if !ok || !lock.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	if _, ok := r.project(organisationID, lock.ProjectID); !ok {
+		return nil
+	}
+	found := *lock
+	found.Username = r.usernames[lock.UserID]
+	return &found
+}
+
+func (r *MemoryProjectRepository) AcquireLock(ctx context.Context, organisationID int64, projectID int64, objectType string, objectID int64, userID int64, ttl time.Duration) (EditLock, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	key := memoryLockKey{ObjectType: objectType, ObjectID: objectID}
+	lock, ok := r.locks[key]
+	switch {
+	case !ok:
+		r.locks[key] = &EditLock{
+			ID:         r.newID(),
+			ProjectID:  projectID,
+			ObjectType: objectType,
+			ObjectID:   objectID,
+			UserID:     userID,
+			AcquiredAt: now,
+			ExpiresAt:  now.Add(ttl),
+		}
+	case lock.UserID == userID && lock.ExpiresAt.After(now):
+		lock.ExpiresAt = now.Add(ttl)
+	case !lock.ExpiresAt.After(now):
+		lock.UserID = userID
+		lock.AcquiredAt = now
+		lock.ExpiresAt = now.Add(ttl)
+	}
+
+	active := r.activeLock(organisationID, objectType, objectID)
+	if active.UserID != userID {
+		return *active, ErrLockHeld
+	}
+	return *active, nil
+}
+
+func (r *MemoryProjectRepository) ReleaseLock(ctx context.Context, organisationID int64, objectType string, objectID int64, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := memoryLockKey{ObjectType: objectType, ObjectID: objectID}
+	if lock, ok := r.locks[key]; ok && lock.UserID == userID {
+		if _, ok := r.project(organisationID, lock.ProjectID); ok {
+			delete(r.locks, key)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryProjectRepository) CheckLock(ctx context.Context, organisationID int64, objectType string, objectID int64, userID int64, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lock := r.activeLock(organisationID, objectType, objectID)
+	if lock == nil {
+		return nil
+	}
+	if lock.UserID != userID {
+		return ErrLockHeld
+	}
+	r.locks[memoryLockKey{ObjectType: objectType, ObjectID: objectID}].ExpiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+func (r *MemoryProjectRepository) ListActiveLocks(ctx context.Context, organisationID int64) ([]EditLock, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locks := []EditLock{}
+	for key := range r.locks {
+		if lock := r.activeLock(organisationID, key.ObjectType, key.ObjectID); lock != nil {
+			locks = append(locks, *lock)
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].ProjectID != locks[j].ProjectID {
+			return locks[i].ProjectID < locks[j].ProjectID
+		}
+		return locks[i].AcquiredAt.Before(locks[j].AcquiredAt)
+	})
+	return locks, nil
+}
+
+func (r *MemoryProjectRepository) DeleteLock(ctx context.Context, organisationID int64, lockID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, lock := range r.locks {
+		if _, ok := r.project(organisationID, lock.ProjectID); ok && lock.ID == lockID {
+			delete(r.locks, key)
+			return nil
+		}
+	}
+	return ErrLockNotFound
+}
+
+func (r *MemoryProjectRepository) GetCommentRow(ctx context.Context, organisationID int64, commentID int64) (CommentRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, ok := r.comments[commentID]
+	if !ok {
+		return CommentRow{}, ErrCommentNotFound
+	}
+	if _, ok := r.project(organisationID, row.ProjectID); !ok {
+		return CommentRow{}, ErrCommentNotFound
+	}
+	return r.commentRow(row), nil
+}
+
+func (r *MemoryProjectRepository) commentRow(row *CommentRow) CommentRow {
+	found := *row
+	found.AuthorUsername = r.usernames[row.AuthorID]
+	found.Mentions = slices.Clone(row.Mentions)
+	return found
+}
+
+func (r *MemoryProjectRepository) GetCommentRows(ctx context.Context, projectID int64, buildingID *int64) ([]CommentRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rows := []CommentRow{}
+	for _, row := range r.comments {
+		if row.ProjectID != projectID {
+			continue
+		}
+		if buildingID != nil && (!row.BuildingID.Valid || row.BuildingID.Int64 != *buildingID) {
+			continue
+		}
+		rows = append(rows, r.commentRow(row))
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+	return rows, nil
+}
+
+func (r *MemoryProjectRepository) GetProjectMembersByUsernames(ctx context.Context, projectID int64, usernames []string) ([]ProjectMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := []ProjectMember{}
+	project, ok := r.projects[projectID]
+	if !ok {
+		return members, nil
+	}
+	for userID := range project.Members {
+		if username := r.usernames[userID]; slices.Contains(usernames, username) {
+			members = append(members, ProjectMember{ID: userID, Username: username})
+		}
+	}
+	return members, nil
+}
+
+func (r *MemoryProjectRepository) InsertComment(ctx context.Context, comment NewComment, mentionIDs []int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commentID := r.newID()
+	row := &CommentRow{
+		ID:        commentID,
+		ProjectID: comment.ProjectID,
+		AuthorID:  comment.AuthorID,
+		Text:      comment.Text,
+		CreatedAt: time.Now(),
+	}
+	if comment.BuildingID != nil {
+		row.BuildingID = sql.NullInt64{Int64: *comment.BuildingID, Valid: true}
+	}
+	if comment.ParentID != nil {
+		row.ParentID = sql.NullInt64{Int64: *comment.ParentID, Valid: true}
+	}
+	if comment.Point != nil {
+		row.PointX = sql.NullFloat64{Float64: comment.Point.X, Valid: true}
+		row.PointY = sql.NullFloat64{Float64: comment.Point.Y, Valid: true}
+	}
+	for _, userID := range mentionIDs {
+		row.Mentions = append(row.Mentions, r.usernames[userID])
+	}
+	sort.Strings(row.Mentions)
+
+	r.comments[commentID] = row
+	return commentID, nil
+}
+
+func (r *MemoryProjectRepository) SetCommentResolved(ctx context.Context, organisationID int64, commentID int64, resolved bool, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, ok := r.comments[commentID]
+	if !ok {
+		return nil
+	}
+	if _, ok := r.project(organisationID, row.ProjectID); !ok {
+		return nil
+	}
+	row.IsResolved = resolved
+	row.ResolvedByID = sql.NullInt64{}
+	row.ResolvedAt = sql.NullTime{}
+	if resolved {
+		row.ResolvedByID = sql.NullInt64{Int64: userID, Valid: true}
+		row.ResolvedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+func (r *MemoryProjectRepository) InsertShareLink(ctx context.Context, projectID int64, tokenHash string, password string, createdByID int64, expiresAt *time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var organisationID int64
+	if project, ok := r.projects[projectID]; ok {
+		organisationID = project.OrganisationID
+	}
+	linkID := r.newID()
+	r.shareLinks[linkID] = &memoryShareLink{
+		ShareLink: ShareLink{
+			ID:             linkID,
+			ProjectID:      projectID,
+			OrganisationID: organisationID,
+			CreatedByID:    createdByID,
+			CreatedAt:      time.Now(),
+			ExpiresAt:      expiresAt,
+			HasPassword:    password != "",
+			Password:       password,
+		},
+		TokenHash: tokenHash,
+	}
+	return linkID, nil
+}
+
+func (r *MemoryProjectRepository) GetShareLinkByTokenHash(ctx context.Context, tokenHash string) (ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, link := range r.shareLinks {
+		if link.TokenHash == tokenHash {
+			return link.ShareLink, nil
+		}
+	}
+	return ShareLink{}, ErrShareLinkNotFound
+}
+
+func (r *MemoryProjectRepository) GetShareLink(ctx context.Context, organisationID int64, linkID int64) (ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.shareLinks[linkID]
+	if !ok || link.OrganisationID != organisationID {
+		return ShareLink{}, ErrShareLinkNotFound
+	}
+	return link.ShareLink, nil
+}
+
+func (r *MemoryProjectRepository) GetProjectShareLinks(ctx context.Context, organisationID int64, projectID int64) ([]ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	links := []ShareLink{}
+	for _, link := range r.shareLinks {
+		if link.ProjectID == projectID && link.OrganisationID == organisationID {
+			links = append(links, link.ShareLink)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID > links[j].ID })
+	return links, nil
+}
+
+func (r *MemoryProjectRepository) MarkShareLinkRevoked(ctx context.Context, organisationID int64, linkID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if link, ok := r.shareLinks[linkID]; ok && link.OrganisationID == organisationID && link.RevokedAt == nil {
+		now := time.Now()
+		link.RevokedAt = &now
+	}
+	return nil
+}
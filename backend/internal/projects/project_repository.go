@@ -0,0 +1,59 @@
+package projects
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// ProjectRepository is the storage behind the project handlers: projects and
+// their objects, members, edit locks, comments and share links.
+// PostgresProjectRepository works on the tables of the Django admin,
+// MemoryProjectRepository keeps everything in memory for tests.
+type ProjectRepository interface {
+	GetProjectDetails(ctx context.Context, organisationID int64, projectID int64) (ProjectDetails, error)
+	InsertProject(ctx context.Context, organisationID int64, name string, userID int64) (int64, error)
+	InsertBuilding(ctx context.Context, organisationID int64, projectID int64, coordinates string) (int64, error)
+	InsertPlayground(ctx context.Context, organisationID int64, projectID int64, coordinates string) (int64, error)
+	UpdateBuilding(ctx context.Context, organisationID int64, buildingID int64, coordinates string, floors int64, floorsHeight float64) error
+	UpdatePlayground(ctx context.Context, organisationID int64, playgroundID int64, coordinates string) error
+	ProjectExists(ctx context.Context, organisationID int64, projectID int64) (bool, error)
+
+	GetProjectRole(ctx context.Context, organisationID int64, projectID int64, userID int64) (string, error)
+	GetProjectMembers(ctx context.Context, organisationID int64, projectID int64) ([]Member, error)
+	InsertProjectMember(ctx context.Context, organisationID int64, projectID int64, username string, role string) (Member, error)
+	CountProjectOwners(ctx context.Context, organisationID int64, projectID int64) (int, error)
+	UpdateProjectMemberRole(ctx context.Context, organisationID int64, projectID int64, userID int64, role string) error
+	DeleteProjectMember(ctx context.Context, organisationID int64, projectID int64, userID int64) error
+
+	GetObjectProjectID(ctx context.Context, organisationID int64, objectType string, objectID int64) (int64, error)
+	GetActiveLock(ctx context.Context, organisationID int64, objectType string, objectID int64) (*EditLock, error)
+	AcquireLock(ctx context.Context, organisationID int64, projectID int64, objectType string, objectID int64, userID int64, ttl time.Duration) (EditLock, error)
+	ReleaseLock(ctx context.Context, organisationID int64, objectType string, objectID int64, userID int64) error
+	CheckLock(ctx context.Context, organisationID int64, objectType string, objectID int64, userID int64, ttl time.Duration) error
+	ListActiveLocks(ctx context.Context, organisationID int64) ([]EditLock, error)
+	DeleteLock(ctx context.Context, organisationID int64, lockID int64) error
+
+	GetCommentRow(ctx context.Context, organisationID int64, commentID int64) (CommentRow, error)
+	GetCommentRows(ctx context.Context, projectID int64, buildingID *int64) ([]CommentRow, error)
+	GetProjectMembersByUsernames(ctx context.Context, projectID int64, usernames []string) ([]ProjectMember, error)
+	InsertComment(ctx context.Context, comment NewComment, mentionIDs []int64) (int64, error)
+	SetCommentResolved(ctx context.Context, organisationID int64, commentID int64, resolved bool, userID int64) error
+
+	InsertShareLink(ctx context.Context, projectID int64, tokenHash string, password string, createdByID int64, expiresAt *time.Time) (int64, error)
+	GetShareLinkByTokenHash(ctx context.Context, tokenHash string) (ShareLink, error)
+	GetShareLink(ctx context.Context, organisationID int64, linkID int64) (ShareLink, error)
+	GetProjectShareLinks(ctx context.Context, organisationID int64, projectID int64) ([]ShareLink, error)
+	MarkShareLinkRevoked(ctx context.Context, organisationID int64, linkID int64) error
+}
+
+type PostgresProjectRepository struct {
+	db           *sqlx.DB
+	queryTimeout time.Duration
+}
+
+// NewPostgresProjectRepository returns a repository whose every method gives up
+// after queryTimeout, or earlier when its context ends.
+func NewPostgresProjectRepository(db *sqlx.DB, queryTimeout time.Duration) *PostgresProjectRepository {
+	return &PostgresProjectRepository{db: db, queryTimeout: queryTimeout}
+}
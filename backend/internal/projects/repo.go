@@ -1,56 +1,103 @@
 package projects
 
 import (
+	"3d-backend/internal/tracing"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ProjectDetails is a project with its buildings, ordered by ID, and its
+// playground, if it has one.
 type ProjectDetails struct {
-	BuildingID            sql.NullInt64   `db:"building_id"`
-	BuildingProjectID     sql.NullInt64   `db:"building_project_id"`
-	BuildingCoordinates   sql.NullString  `db:"building_coordinates"`
-	BuildingFloors        sql.NullInt64   `db:"building_floors"`
-	BuildingFloorsHeight  sql.NullFloat64 `db:"building_floors_height"`
-	PlaygroundID          sql.NullInt64   `db:"playground_id"`
-	PlaygroundProjectID   sql.NullInt64   `db:"playground_project_id"`
-	PlaygroundCoordinates sql.NullString  `db:"playground_coordinates"`
+	ID             int64
+	Name           string
+	OrganisationID int64
+	Buildings      []Building
+	Playground     *Playground
 }
 
-func GetProjectDetails(db *sqlx.DB, projectID int64) ([]ProjectDetails, error) {
-	var details []ProjectDetails
+// ErrProjectNotFound is returned when the project or object does not exist in
+// the caller's organisation.
+var ErrProjectNotFound = errors.New("project not found")
+
+// GetProjectDetails reads the project in one round trip. Buildings and the
+// playground are aggregated to JSON in subqueries, so a project with many
+// buildings is a single row instead of one row per building and playground.
+// The span reports the buildings read as db.rows.
+func (r *PostgresProjectRepository) GetProjectDetails(ctx context.Context, organisationID int64, projectID int64) (details ProjectDetails, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "projects.GetProjectDetails", tracing.ProjectID(projectID))
+	defer func() { tracing.EndQuery(span, int64(len(details.Buildings)), err) }()
+
 	query := `
-		SELECT 
-			b.id AS building_id,
-			b.project_id AS building_project_id,
-			b.coordinates AS building_coordinates,
-			b.floors AS building_floors,
-			b.floors_height AS building_floors_height,
-			p.id AS playground_id,
-			p.project_id AS playground_project_id,
-			p.coordinates AS playground_coordinates
-		FROM 
-			projects_project pr
-		LEFT JOIN 
-			projects_building b ON pr.id = b.project_id
-		LEFT JOIN 
-			projects_playground p ON pr.id = p.project_id
-		WHERE 
-			pr.id = $1;
+		SELECT
+			pr.id,
+			pr.name,
+			pr.organisation_id,
+			COALESCE((
+				SELECT json_agg(json_build_object(
+					'id', b.id,
+					'project_id', b.project_id,
+					'coordinates', b.coordinates,
+					'floors', b.floors,
+					'floors_height', b.floors_height
+				) ORDER BY b.id)
+				FROM projects_building b
+				WHERE b.project_id = pr.id
+			), '[]') AS buildings,
+			(
+				SELECT json_build_object(
+					'id', p.id,
+					'project_id', p.project_id,
+					'coordinates', p.coordinates
+				)
+				FROM projects_playground p
+				WHERE p.project_id = pr.id
+			) AS playground
+		FROM projects_project pr
+		WHERE pr.id = $1 AND pr.organisation_id = $2;
 	`
-	err := db.Select(&details, query, projectID)
+	var row struct {
+		ID             int64  `db:"id"`
+		Name           string `db:"name"`
+		OrganisationID int64  `db:"organisation_id"`
+		Buildings      []byte `db:"buildings"`
+		Playground     []byte `db:"playground"`
+	}
+	err = r.db.GetContext(ctx, &row, query, projectID, organisationID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("no details found for project %d", projectID)
+			return details, ErrProjectNotFound
+		}
+		return details, err
+	}
+
+	details = ProjectDetails{ID: row.ID, Name: row.Name, OrganisationID: row.OrganisationID}
+	if err := json.Unmarshal(row.Buildings, &details.Buildings); err != nil {
+		return ProjectDetails{}, fmt.Errorf("failed to decode buildings: %w", err)
+	}
+	if row.Playground != nil {
+		if err := json.Unmarshal(row.Playground, &details.Playground); err != nil {
+			return ProjectDetails{}, fmt.Errorf("failed to decode playground: %w", err)
 		}
-		return nil, err
 	}
 	return details, nil
 }
 
-func InsertProject(db *sqlx.DB, name string, userID int64) (int64, error) {
-	tx, err := db.Beginx()
+func (r *PostgresProjectRepository) InsertProject(ctx context.Context, organisationID int64, name string, userID int64) (projectID int64, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "projects.InsertProject")
+	defer func() { tracing.EndQuery(span, 1, err) }()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to start transaction: %w", err)
 	}
@@ -58,22 +105,21 @@ func InsertProject(db *sqlx.DB, name string, userID int64) (int64, error) {
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO projects_project (name)
-		VALUES ($1)
+		INSERT INTO projects_project (name, organisation_id)
+		VALUES ($1, $2)
 		RETURNING id;
 	`
 
-	var projectID int64
-	err = tx.Get(&projectID, query, name)
+	err = tx.GetContext(ctx, &projectID, query, name, organisationID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create project: %w", err)
 	}
 
 	relationshipQuery := `
-		INSERT INTO projects_project_user (project_id, user_id)
-		VALUES ($1, $2);
+		INSERT INTO projects_project_user (project_id, user_id, role)
+		VALUES ($1, $2, $3);
 	`
-	_, err = tx.Exec(relationshipQuery, projectID, userID)
+	_, err = tx.ExecContext(ctx, relationshipQuery, projectID, userID, RoleOwner)
 	if err != nil {
 		return 0, fmt.Errorf("failed to associate user with project: %w", err)
 	}
@@ -82,64 +128,122 @@ func InsertProject(db *sqlx.DB, name string, userID int64) (int64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	span.SetAttributes(tracing.ProjectID(projectID))
 
 	return projectID, nil
 }
 
-func InsertBuilding(db *sqlx.DB, projectID int64, coordinates string) (int64, error) {
+func (r *PostgresProjectRepository) InsertBuilding(ctx context.Context, organisationID int64, projectID int64, coordinates string) (buildingID int64, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "projects.InsertBuilding", tracing.ProjectID(projectID))
+	defer func() { tracing.EndQuery(span, 1, err) }()
+
 	query := `
 		INSERT INTO projects_building (project_id, coordinates, floors, floors_height)
-		VALUES ($1, $2, 1, 3)
+		SELECT pr.id, $2, 1, 3
+		FROM projects_project pr
+		WHERE pr.id = $1 AND pr.organisation_id = $3
 		RETURNING id;
 	`
-	var buildingID int64
-	err := db.Get(&buildingID, query, projectID, coordinates)
+	err = r.db.GetContext(ctx, &buildingID, query, projectID, coordinates, organisationID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrProjectNotFound
+		}
 		return 0, err
 	}
 	return buildingID, nil
 }
 
-func InsertPlayground(db *sqlx.DB, projectID int64, coordinates string) (int64, error) {
+func (r *PostgresProjectRepository) InsertPlayground(ctx context.Context, organisationID int64, projectID int64, coordinates string) (playgroundID int64, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "projects.InsertPlayground", tracing.ProjectID(projectID))
+	defer func() { tracing.EndQuery(span, 1, err) }()
+
 	query := `
 		INSERT INTO projects_playground (project_id, coordinates)
-		VALUES ($1, $2)
+		SELECT pr.id, $2
+		FROM projects_project pr
+		WHERE pr.id = $1 AND pr.organisation_id = $3
 		RETURNING id;
 	`
-	var playgroundID int64
-	err := db.Get(&playgroundID, query, projectID, coordinates)
+	err = r.db.GetContext(ctx, &playgroundID, query, projectID, coordinates, organisationID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrProjectNotFound
+		}
 		return 0, err
 	}
 	return playgroundID, nil
 }
 
-func UpdateBuilding(db *sqlx.DB, buildingID int64, coordinates string, floors int64, floorsHeight float64) error {
+func (r *PostgresProjectRepository) UpdateBuilding(ctx context.Context, organisationID int64, buildingID int64, coordinates string, floors int64, floorsHeight float64) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "projects.UpdateBuilding", attribute.Int64("building.id", buildingID))
+	var rows int64
+	defer func() { tracing.EndQuery(span, rows, err) }()
+
 	query := `
-		UPDATE projects_building
+		UPDATE projects_building b
 		SET coordinates = $1, floors = $2, floors_height = $3
-		WHERE id = $4;
+		FROM projects_project pr
+		WHERE b.id = $4 AND pr.id = b.project_id AND pr.organisation_id = $5;
 	`
 
-	_, err := db.Exec(query, coordinates, floors, floorsHeight, buildingID)
+	res, err := r.db.ExecContext(ctx, query, coordinates, floors, floorsHeight, buildingID, organisationID)
 	if err != nil {
 		return fmt.Errorf("failed to update building: %w", err)
 	}
+	if rows, _ = res.RowsAffected(); rows == 0 {
+		return ErrProjectNotFound
+	}
 
 	return nil
 }
 
-func UpdatePlayground(db *sqlx.DB, playgroundID int64, coordinates string) error {
+func (r *PostgresProjectRepository) UpdatePlayground(ctx context.Context, organisationID int64, playgroundID int64, coordinates string) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "projects.UpdatePlayground", attribute.Int64("playground.id", playgroundID))
+	var rows int64
+	defer func() { tracing.EndQuery(span, rows, err) }()
+
 	query := `
-		UPDATE projects_playground
+		UPDATE projects_playground p
 		SET coordinates = $1
-		WHERE id = $2;
+		FROM projects_project pr
+		WHERE p.id = $2 AND pr.id = p.project_id AND pr.organisation_id = $3;
 	`
 
-	_, err := db.Exec(query, coordinates, playgroundID)
+	res, err := r.db.ExecContext(ctx, query, coordinates, playgroundID, organisationID)
 	if err != nil {
 		return fmt.Errorf("failed to update building: %w", err)
 	}
+	if rows, _ = res.RowsAffected(); rows == 0 {
+		return ErrProjectNotFound
+	}
 
 	return nil
 }
+
+func (r *PostgresProjectRepository) ProjectExists(ctx context.Context, organisationID int64, projectID int64) (exists bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	ctx, span := tracing.StartQuery(ctx, "projects.ProjectExists", tracing.ProjectID(projectID))
+	defer func() { tracing.EndQuery(span, 1, err) }()
+
+	query := `SELECT EXISTS (SELECT 1 FROM projects_project WHERE id = $1 AND organisation_id = $2)`
+	err = r.db.GetContext(ctx, &exists, query, projectID, organisationID)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
@@ -0,0 +1,201 @@
+package projects
+
+import (
+	"3d-backend/internal/migrate"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDB connects to TEST_DB_DSN with a fresh, migrated schema as search path.
+// The schema is dropped after the test.
+func testDB(tb testing.TB) *sqlx.DB {
+	tb.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		tb.Skip("TEST_DB_DSN is not set")
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		tb.Fatal(err)
+	}
+	schema := "test_projects_" + hex.EncodeToString(suffix)
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		dsn += " search_path=" + schema
+	} else {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		dsn = u.String()
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.NewMigrator(db)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// seedProject creates a user, an organisation and a project owned by the user.
+func seedProject(tb testing.TB, db *sqlx.DB) (organisationID int64, projectID int64) {
+	tb.Helper()
+
+	_, err := db.Exec(`
+		INSERT INTO auth_user (id, password, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined)
+		VALUES (1, '', false, 'alice', '', '', '', false, true, now())
+	`)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	err = db.Get(&organisationID, `INSERT INTO projects_organisation (name, created_at, require_2fa) VALUES ('Org', now(), false) RETURNING id`)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	projectID, err = NewPostgresProjectRepository(db, time.Minute).InsertProject(context.Background(), organisationID, "Site", 1)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return organisationID, projectID
+}
+
+// TestGetProjectDetailsCancelled holds a lock on the buildings, so the query
+// waits until its context is cancelled.
+func TestGetProjectDetailsCancelled(t *testing.T) {
+	db := testDB(t)
+	organisationID, projectID := seedProject(t, db)
+	projects := NewPostgresProjectRepository(db, time.Minute)
+
+	lock, err := db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Rollback()
+	if _, err := lock.Exec(`LOCK TABLE projects_building IN ACCESS EXCLUSIVE MODE`); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = projects.GetProjectDetails(ctx, organisationID, projectID)
+	if err == nil {
+		t.Fatal("query succeeded despite the lock")
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("context error = %v, want %v", ctx.Err(), context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("query returned after %s, not when cancelled", elapsed)
+	}
+}
+
+func TestCheckLock(t *testing.T) {
+	db := testDB(t)
+	organisationID, projectID := seedProject(t, db)
+	projects := NewPostgresProjectRepository(db, time.Minute)
+	ctx := context.Background()
+
+	_, err := db.Exec(`
+		INSERT INTO auth_user (id, password, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined)
+		VALUES (2, '', false, 'bob', '', '', '', false, true, now())
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildingID, err := projects.InsertBuilding(ctx, organisationID, projectID, "[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := projects.CheckLock(ctx, organisationID, LockObjectBuilding, buildingID, 1, time.Minute); err != nil {
+		t.Fatalf("unlocked object: %v", err)
+	}
+
+	lock, err := projects.AcquireLock(ctx, organisationID, projectID, LockObjectBuilding, buildingID, 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := projects.CheckLock(ctx, organisationID, LockObjectBuilding, buildingID, 1, time.Hour); err != nil {
+		t.Fatalf("own lock: %v", err)
+	}
+	extended, err := projects.GetActiveLock(ctx, organisationID, LockObjectBuilding, buildingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !extended.ExpiresAt.After(lock.ExpiresAt.Add(time.Minute)) {
+		t.Errorf("lock expires at %s, not extended from %s", extended.ExpiresAt, lock.ExpiresAt)
+	}
+
+	if err := projects.CheckLock(ctx, organisationID, LockObjectBuilding, buildingID, 2, time.Minute); err != ErrLockHeld {
+		t.Errorf("lock of another user: error = %v, want %v", err, ErrLockHeld)
+	}
+
+	if _, err := db.Exec(`UPDATE projects_editlock SET expires_at = now() - interval '1 second'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := projects.CheckLock(ctx, organisationID, LockObjectBuilding, buildingID, 2, time.Minute); err != nil {
+		t.Errorf("expired lock of another user: %v", err)
+	}
+}
+
+func BenchmarkGetProjectDetails(b *testing.B) {
+	db := testDB(b)
+	organisationID, projectID := seedProject(b, db)
+	projects := NewPostgresProjectRepository(db, time.Minute)
+
+	const buildings = 5000
+	_, err := db.Exec(`
+		INSERT INTO projects_building (project_id, coordinates, floors, floors_height)
+		SELECT $1, jsonb_build_array(jsonb_build_object('x', i, 'y', i), jsonb_build_object('x', i + 1, 'y', i)), 5, 3.0
+		FROM generate_series(1, $2) AS i
+	`, projectID, buildings)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := db.Exec(`ANALYZE projects_building`); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for range b.N {
+		details, err := projects.GetProjectDetails(ctx, organisationID, projectID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(details.Buildings) != buildings {
+			b.Fatalf("got %d buildings, want %d", len(details.Buildings), buildings)
+		}
+	}
+}
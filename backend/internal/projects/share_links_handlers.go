@@ -0,0 +1,251 @@
+package projects
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/auth"
+	"3d-backend/internal/organisations"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type createShareLinkInput struct {
+	ProjectID int64      `json:"project_id" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Password  string     `json:"password"`
+}
+
+type createShareLinkResponse struct {
+	ShareLinkID int64  `json:"share_link_id"`
+	Token       string `json:"token"`
+}
+
+type revokeShareLinkInput struct {
+	ShareLinkID int64 `json:"share_link_id" binding:"required"`
+}
+
+// newShareToken returns a random token signed with the share link secret, so
+// forged tokens are rejected before touching the database.
+func newShareToken(secret []byte) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(nonce)
+	return payload + "." + signShareToken(secret, payload), nil
+}
+
+func signShareToken(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("share:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verifyShareToken(secret []byte, token string) error {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return errors.New("malformed share token")
+	}
+	if !hmac.Equal([]byte(signature), []byte(signShareToken(secret, payload))) {
+		return errors.New("invalid share token signature")
+	}
+	return nil
+}
+
+// hashShareToken is what gets stored, so a leaked table does not leak links.
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateShareLink godoc
+// @Summary Создание публичной ссылки на проект
+// @Tags share
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body createShareLinkInput true "Share link information"
+// @Success 200 {object} createShareLinkResponse
+// @Security BearerAuth
+// @Router /project/create-share-link [post]
+func (h *Handler) CreateShareLink(c *gin.Context) {
+	var input createShareLinkInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, input.ProjectID, RoleOwner)
+	if !ok {
+		return
+	}
+
+	token, err := newShareToken(h.cfg.ShareLinkSecret)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate token").WithCause(err))
+		return
+	}
+
+	var passwordHash string
+	if input.Password != "" {
+		passwordHash, err = auth.HashDjangoPassword(input.Password)
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to hash password").WithCause(err))
+			return
+		}
+	}
+
+	linkID, err := h.projects.InsertShareLink(c.Request.Context(), input.ProjectID, hashShareToken(token), passwordHash, access.UserID, input.ExpiresAt)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed create share link").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, createShareLinkResponse{
+		ShareLinkID: linkID,
+		Token:       token,
+	})
+}
+
+// ListShareLinks godoc
+// @Summary Публичные ссылки проекта
+// @Tags share
+// @Accept */*
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param project_id query int true "Project ID"
+// @Success 200 {array} ShareLink "Share links"
+// @Security BearerAuth
+// @Router /project/share-links [get]
+func (h *Handler) ListShareLinks(c *gin.Context) {
+	projectID, err := strconv.ParseInt(c.Query("project_id"), 10, 64)
+	if err != nil {
+		apperr.Respond(c, apperr.InvalidField("project_id", "integer", "Invalid project_id"))
+		return
+	}
+
+	access, ok := h.authorizeProject(c, projectID, RoleOwner)
+	if !ok {
+		return
+	}
+
+	links, err := h.projects.GetProjectShareLinks(c.Request.Context(), access.OrganisationID, projectID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get share links").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// RevokeShareLink godoc
+// @Summary Отзыв публичной ссылки
+// @Tags share
+// @Accept json
+// @Produce json
+// @Param X-Organisation-ID header int false "Organisation ID"
+// @Param input body revokeShareLinkInput true "Share link"
+// @Security BearerAuth
+// @Router /project/revoke-share-link [post]
+func (h *Handler) RevokeShareLink(c *gin.Context) {
+	var input revokeShareLinkInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Binding(err))
+		return
+	}
+
+	organisationID, err := organisations.GetOrganisationID(c)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get organisation").WithCause(err))
+		return
+	}
+
+	link, err := h.projects.GetShareLink(c.Request.Context(), organisationID, input.ShareLinkID)
+	if errors.Is(err, ErrShareLinkNotFound) {
+		apperr.Respond(c, apperr.NotFound("Share link not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get share link").WithCause(err))
+		return
+	}
+
+	if _, ok := h.authorizeProject(c, link.ProjectID, RoleOwner); !ok {
+		return
+	}
+
+	err = h.projects.MarkShareLinkRevoked(c.Request.Context(), organisationID, link.ID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to revoke share link").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
+
+// GetSharedProject godoc
+// @Summary Просмотр проекта по публичной ссылке
+// @Tags share
+// @Accept */*
+// @Produce json
+// @Param token query string true "Share token"
+// @Param X-Share-Password header string false "Share link password"
+// @Success 200 {object} projectDetailsResponse "Project Details"
+// @Failure 401 {object} apperr.Response "Invalid, expired or protected link"
+// @Failure 429 {object} apperr.Response "Too many requests"
+// @Router /share/project-details [get]
+func (h *Handler) GetSharedProject(c *gin.Context) {
+	token := c.Query("token")
+
+	if err := verifyShareToken(h.cfg.ShareLinkSecret, token); err != nil {
+		apperr.Respond(c, apperr.Unauthenticated("Invalid share link"))
+		return
+	}
+
+	link, err := h.projects.GetShareLinkByTokenHash(c.Request.Context(), hashShareToken(token))
+	if errors.Is(err, ErrShareLinkNotFound) {
+		apperr.Respond(c, apperr.Unauthenticated("Invalid share link"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get share link").WithCause(err))
+		return
+	}
+	c.Set("project_id", link.ProjectID)
+
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now())) {
+		apperr.Respond(c, apperr.Unauthenticated("Share link expired"))
+		return
+	}
+
+	if link.HasPassword {
+		isValid, err := auth.VerifyDjangoPassword(c.GetHeader("X-Share-Password"), link.Password)
+		if err != nil || !isValid {
+			apperr.Respond(c, apperr.Unauthenticated("Share link password required"))
+			return
+		}
+	}
+
+	response, err := h.loadProjectDetails(c.Request.Context(), link.OrganisationID, link.ProjectID)
+	if errors.Is(err, ErrProjectNotFound) {
+		apperr.Respond(c, apperr.NotFound("Project not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to get project details").WithCause(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
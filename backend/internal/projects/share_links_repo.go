@@ -0,0 +1,124 @@
+package projects
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrShareLinkNotFound = errors.New("share link not found")
+
+type ShareLink struct {
+	ID             int64      `db:"id" json:"id"`
+	ProjectID      int64      `db:"project_id" json:"project_id"`
+	OrganisationID int64      `db:"organisation_id" json:"-"`
+	CreatedByID    int64      `db:"created_by_id" json:"created_by_id"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt      *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt      *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	HasPassword    bool       `db:"has_password" json:"has_password"`
+	Password       string     `db:"password" json:"-"`
+}
+
+func (r *PostgresProjectRepository) InsertShareLink(ctx context.Context, projectID int64, tokenHash string, password string, createdByID int64, expiresAt *time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO projects_sharelink (project_id, token_hash, password, created_by_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, now(), $5)
+		RETURNING id;
+	`
+	var linkID int64
+	err := r.db.GetContext(ctx, &linkID, query, projectID, tokenHash, password, createdByID, expiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create share link: %w", err)
+	}
+	return linkID, nil
+}
+
+func (r *PostgresProjectRepository) GetShareLinkByTokenHash(ctx context.Context, tokenHash string) (ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var link ShareLink
+	query := `
+		SELECT
+			l.id, l.project_id, pr.organisation_id, l.created_by_id, l.created_at, l.expires_at, l.revoked_at,
+			l.password <> '' AS has_password, l.password
+		FROM projects_sharelink l
+		JOIN projects_project pr ON pr.id = l.project_id
+		WHERE l.token_hash = $1;
+	`
+	err := r.db.GetContext(ctx, &link, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return link, ErrShareLinkNotFound
+		}
+		return link, err
+	}
+	return link, nil
+}
+
+func (r *PostgresProjectRepository) GetShareLink(ctx context.Context, organisationID int64, linkID int64) (ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var link ShareLink
+	query := `
+		SELECT
+			l.id, l.project_id, pr.organisation_id, l.created_by_id, l.created_at, l.expires_at, l.revoked_at,
+			l.password <> '' AS has_password, l.password
+		FROM projects_sharelink l
+		JOIN projects_project pr ON pr.id = l.project_id
+		WHERE l.id = $1 AND pr.organisation_id = $2;
+	`
+	err := r.db.GetContext(ctx, &link, query, linkID, organisationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return link, ErrShareLinkNotFound
+		}
+		return link, err
+	}
+	return link, nil
+}
+
+func (r *PostgresProjectRepository) GetProjectShareLinks(ctx context.Context, organisationID int64, projectID int64) ([]ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	links := []ShareLink{}
+	query := `
+		SELECT
+			l.id, l.project_id, pr.organisation_id, l.created_by_id, l.created_at, l.expires_at, l.revoked_at,
+			l.password <> '' AS has_password, l.password
+		FROM projects_sharelink l
+		JOIN projects_project pr ON pr.id = l.project_id
+		WHERE l.project_id = $1 AND pr.organisation_id = $2
+		ORDER BY l.created_at DESC;
+	`
+	err := r.db.SelectContext(ctx, &links, query, projectID, organisationID)
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *PostgresProjectRepository) MarkShareLinkRevoked(ctx context.Context, organisationID int64, linkID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE projects_sharelink l
+		SET revoked_at = now()
+		FROM projects_project pr
+		WHERE pr.id = l.project_id AND l.id = $1 AND l.revoked_at IS NULL AND pr.organisation_id = $2;
+	`
+	_, err := r.db.ExecContext(ctx, query, linkID, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	return nil
+}
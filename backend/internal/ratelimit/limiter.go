@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"3d-backend/internal/apperr"
+	"3d-backend/internal/logging"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Quota allows Limit requests per Window for every key.
+type Quota struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// KeyFunc picks what a quota is counted against.
+type KeyFunc func(c *gin.Context) string
+
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser counts per authenticated user and falls back to the client IP.
+func ByUser(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return ByIP(c)
+}
+
+// Middleware rejects requests over the quota with 429 and Retry-After. When
+// the store fails the request is let through rather than taking the API down.
+func Middleware(store Store, quota Quota, keyFunc KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := quota.Name + ":" + keyFunc(c)
+
+		count, windowEndsAt, err := store.Increment(c.Request.Context(), key, quota.Window)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Rate limit unavailable", "quota", quota.Name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(quota.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(max(quota.Limit-count, 0)))
+
+		if count > quota.Limit {
+			TooManyRequests(c, time.Until(windowEndsAt))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TooManyRequests answers with 429 and Retry-After and aborts the chain.
+func TooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	apperr.Respond(c, apperr.RateLimited(fmt.Sprintf("Too many requests, retry in %d seconds", seconds)))
+}
+
+// Backoff throttles repeated failures such as wrong passwords. The first
+// FreeAttempts failures within Window are free, every further one blocks the
+// key for BaseDelay doubled per failure up to MaxDelay, and reaching
+// LockoutAfter failures locks the key out for Lockout.
+type Backoff struct {
+	Name         string
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockoutAfter int
+	Lockout      time.Duration
+	Window       time.Duration
+}
+
+// Check returns how long key is still blocked.
+func (b Backoff) Check(ctx context.Context, store Store, key string) (time.Duration, error) {
+	until, err := store.BlockedUntil(ctx, b.Name+":"+key)
+	if err != nil {
+		return 0, err
+	}
+	if until.IsZero() {
+		return 0, nil
+	}
+	return time.Until(until), nil
+}
+
+// Fail records a failure and returns the block it caused, if any.
+func (b Backoff) Fail(ctx context.Context, store Store, key string) (time.Duration, error) {
+	key = b.Name + ":" + key
+
+	failures, _, err := store.Increment(ctx, key, b.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	delay := b.delay(failures)
+	if delay == 0 {
+		return 0, nil
+	}
+	return delay, store.Block(ctx, key, time.Now().Add(delay))
+}
+
+// Succeed clears the failures of key.
+func (b Backoff) Succeed(ctx context.Context, store Store, key string) error {
+	return store.Reset(ctx, b.Name+":"+key)
+}
+
+func (b Backoff) delay(failures int) time.Duration {
+	if b.LockoutAfter > 0 && failures >= b.LockoutAfter {
+		return b.Lockout
+	}
+	if failures <= b.FreeAttempts {
+		return 0
+	}
+
+	delay := b.BaseDelay << min(failures-b.FreeAttempts-1, 30)
+	if delay > b.MaxDelay || delay <= 0 {
+		delay = b.MaxDelay
+	}
+	return delay
+}
+
+// StartCleanup drops expired entries from the store every interval.
+func StartCleanup(store Store, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := store.Cleanup(context.Background()); err != nil {
+				slog.Error("Failed to clean up rate limits", "error", err)
+			}
+		}
+	}()
+}
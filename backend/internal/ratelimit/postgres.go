@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type PostgresStore struct {
+	db           *sqlx.DB
+	queryTimeout time.Duration
+}
+
+func NewPostgresStore(db *sqlx.DB, queryTimeout time.Duration) *PostgresStore {
+	return &PostgresStore{db: db, queryTimeout: queryTimeout}
+}
+
+func (s *PostgresStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_ratelimitbucket AS b (key, count, window_ends_at)
+		VALUES ($1, 1, now() + $2 * interval '1 second')
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN b.window_ends_at <= now() THEN 1 ELSE b.count + 1 END,
+			window_ends_at = CASE WHEN b.window_ends_at <= now() THEN EXCLUDED.window_ends_at ELSE b.window_ends_at END
+		RETURNING count, window_ends_at;
+	`
+	var row struct {
+		Count        int       `db:"count"`
+		WindowEndsAt time.Time `db:"window_ends_at"`
+	}
+	err := s.db.GetContext(ctx, &row, query, key, window.Seconds())
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to increment rate limit: %w", err)
+	}
+	return row.Count, row.WindowEndsAt, nil
+}
+
+func (s *PostgresStore) Block(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts_ratelimitbucket (key, count, window_ends_at, blocked_until)
+		VALUES ($1, 0, now(), $2)
+		ON CONFLICT (key) DO UPDATE SET blocked_until = EXCLUDED.blocked_until;
+	`
+	_, err := s.db.ExecContext(ctx, query, key, until)
+	if err != nil {
+		return fmt.Errorf("failed to block: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	var until time.Time
+	query := `
+		SELECT blocked_until FROM accounts_ratelimitbucket
+		WHERE key = $1 AND blocked_until > now();
+	`
+	err := s.db.GetContext(ctx, &until, query, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return until, nil
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM accounts_ratelimitbucket WHERE key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("failed to reset rate limit: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM accounts_ratelimitbucket
+		WHERE window_ends_at <= now() AND (blocked_until IS NULL OR blocked_until <= now());
+	`
+	_, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to clean up rate limits: %w", err)
+	}
+	return nil
+}
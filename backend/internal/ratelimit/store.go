@@ -0,0 +1,22 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps counters and blocks shared by the limiters. MemoryStore serves a
+// single instance, PostgresStore is shared by all instances of a deployment.
+type Store interface {
+	// Increment counts a hit for key in a fixed window starting at the first
+	// hit and returns the count so far and when the window ends.
+	Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	// Block rejects key until the given time.
+	Block(ctx context.Context, key string, until time.Time) error
+	// BlockedUntil returns the end of the current block, or the zero time.
+	BlockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset forgets the counter and block of key.
+	Reset(ctx context.Context, key string) error
+	// Cleanup drops expired counters and blocks.
+	Cleanup(ctx context.Context) error
+}
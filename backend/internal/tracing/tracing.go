@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config selects where spans go. Without an endpoint the global no-op
+// provider of OpenTelemetry stays in place and spans cost next to nothing.
+type Config struct {
+	// Endpoint is the OTLP/HTTP URL of a collector, e.g.
+	// http://otel-collector:4318.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the share of traces started here that are recorded.
+	// Requests carrying a traceparent follow the decision of the caller.
+	SampleRatio float64
+}
+
+// tracer is taken from the global provider, which forwards to the one Setup
+// installs.
+var tracer = otel.Tracer("3d-backend")
+
+// Setup installs the exporter and the W3C propagators. The returned function
+// flushes pending spans and has to be called before the process exits.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// ProjectID is the attribute spans about a single project carry.
+func ProjectID(projectID int64) attribute.KeyValue {
+	return attribute.Int64("project.id", projectID)
+}
+
+// StartQuery starts a span for a repository call. name is the repository
+// method, e.g. projects.GetProjectDetails.
+func StartQuery(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(name)),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// EndQuery records the rows a query returned or changed, or its error, and
+// ends the span.
+func EndQuery(span trace.Span, rows int64, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows", rows))
+	}
+	span.End()
+}